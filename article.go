@@ -0,0 +1,624 @@
+// Package main provides article metadata extraction.
+//
+// This file implements ExtractArticle, which combines the main content
+// found by ExtractContent with page metadata such as the title and byline.
+// Metadata is read from the full document, before unwanted elements are
+// removed, so <head> tags and hidden markup remain available.
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// titleSeparators lists the separators between a page title and the
+	// site name, in order of preference.
+	titleSeparators = []string{" | ", "|", " - ", " – ", " — ", "—", ": "}
+
+	// bylinePattern matches class/id names of elements holding the author.
+	bylinePattern = regexp.MustCompile(`(?i)\b(byline|author)\b`)
+
+	// hCardNamePattern matches the class names of the formatted name inside
+	// an hCard (class="vcard" or "h-card") microformat.
+	hCardNamePattern = regexp.MustCompile(`(?i)(?:^|\s)(?:fn|p-name)(?:\s|$)`)
+
+	// bylinePrefix matches a leading "By" or "Written by" before the author.
+	bylinePrefix = regexp.MustCompile(`(?i)^(?:(?:written|posted)\s+)?by\b[:\s]*`)
+
+	// bylineDate matches a date trailing the author, such as
+	// " on March 1, 2026" or " | 2026-03-01", and everything after it.
+	bylineDate = regexp.MustCompile(`(?i)[\s,|·•–—-]*(?:\b(?:on|posted|updated|published)\b:?\s*)?` +
+		`(?:\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}|\d{4}年\d{1,2}月\d{1,2}日|` +
+		`(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?\s+\d{1,2}(?:st|nd|rd|th)?,?\s+\d{4}|` +
+		`\d{1,2}(?:st|nd|rd|th)?\s+(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?\s+\d{4}).*$`)
+
+	// articleTypes lists the schema.org types whose JSON-LD describes an article.
+	articleTypes = []string{"Article", "NewsArticle", "BlogPosting"}
+
+	// publishedPattern matches class names of <time> elements holding the
+	// publication date.
+	publishedPattern = regexp.MustCompile(`(?i)(date|publish|posted)`)
+
+	// publishedLayouts lists the layouts tried when parsing a publication
+	// date, most specific first.
+	publishedLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+)
+
+// Article is the main content of a page together with its metadata.
+//
+// Invariants:
+//   - JSON-LD Article metadata takes precedence over heuristics
+type Article struct {
+	// Title is the JSON-LD headline, or the page title from <title>, or
+	// the first <h1> when both are absent.
+	Title string
+
+	// Byline is the author, from JSON-LD, <meta name="author">, or an
+	// element with rel="author" or a byline/author class, such as
+	// <address rel="author"> or an hCard <span class="author vcard">.
+	// Empty when not found.
+	Byline string
+
+	// Published is the publication date as written in the page, from
+	// JSON-LD datePublished, <meta property="article:published_time">,
+	// <meta name="date">, or a <time datetime> element with a date-like
+	// class, in that order. Empty when not found.
+	Published string
+
+	// PublishedTime is Published parsed as a date. It is the zero time
+	// when Published is empty or in an unrecognized format.
+	PublishedTime time.Time
+
+	// CanonicalURL is the URL from <link rel="canonical">, or from
+	// <meta property="og:url"> when absent. Empty when not found.
+	CanonicalURL string
+
+	// Content is the extracted main content as HTML, as returned by
+	// ExtractContentWithConfig.
+	Content string
+
+	// WordCount is the number of words in the text of Content. Chinese,
+	// Japanese, and Korean characters count as one word each.
+	WordCount int
+
+	// ReadingTime is the time needed to read Content at the speeds of the
+	// ScoringConfig, rounded to the second.
+	ReadingTime time.Duration
+}
+
+// ExtractArticle extracts the main content and metadata of an HTML page.
+//
+// Postconditions:
+//   - The result is identical to ExtractArticleWithConfig with
+//     DefaultScoringConfig
+func ExtractArticle(rawHTML string) Article {
+	return ExtractArticleWithConfig(rawHTML, DefaultScoringConfig())
+}
+
+// ExtractArticleWithConfig extracts the main content and metadata of an
+// HTML page using cfg.
+//
+// Preconditions:
+//   - rawHTML can be any string, including empty or invalid HTML
+//
+// Invariants:
+//   - Metadata is read before unwanted elements are removed, so JSON-LD
+//     in <script> tags is available
+//
+// Postconditions:
+//   - Content is identical to ExtractContentWithConfig(rawHTML, cfg)
+//   - Fields whose metadata is absent are empty
+//   - If cfg.CleanTitle is set, site-name segments are removed from Title
+func ExtractArticleWithConfig(rawHTML string, cfg ScoringConfig) Article {
+	content, _ := extractContent(rawHTML, &cfg)
+	article := Article{Content: content}
+
+	doc, err := html.Parse(strings.NewReader(rawHTML))
+	if err != nil {
+		return article
+	}
+
+	h1 := ""
+	if n := findElement(doc, "h1"); n != nil {
+		h1 = collapseText(getTextContent(n))
+	}
+	article.Title = h1
+	if n := findElement(doc, "title"); n != nil {
+		if title := collapseText(getTextContent(n)); title != "" {
+			article.Title = title
+			if cfg.CleanTitle {
+				article.Title = cleanTitle(title, h1)
+			}
+		}
+	}
+	article.Byline = findByline(doc)
+	article.CanonicalURL = findCanonicalURL(doc)
+	article.Published = findPublished(doc)
+
+	if ld, ok := findJSONLDArticle(doc); ok {
+		if headline := collapseText(ld.Headline); headline != "" {
+			article.Title = headline
+		}
+		if author := ld.authorName(); author != "" {
+			article.Byline = author
+		}
+		if published := strings.TrimSpace(ld.DatePublished); published != "" {
+			article.Published = published
+		}
+	}
+	article.PublishedTime = parsePublished(article.Published)
+
+	words, cjk := countWords(content)
+	article.WordCount = words + cjk
+	minutes := float64(words)/float64(cfg.wordsPerMinute()) + float64(cjk)/float64(cfg.cjkCharsPerMinute())
+	article.ReadingTime = time.Duration(minutes * float64(time.Minute)).Round(time.Second)
+	return article
+}
+
+// countWords counts the words in the text of an HTML fragment.
+//
+// Invariants:
+//   - Words are split on whitespace; a run of characters counts as a word
+//     only if it holds a letter or digit, so stray punctuation is ignored
+//   - Each Han, Hiragana, Katakana, or Hangul character is counted in cjk
+//     instead, since those scripts do not separate words with spaces
+//   - Text nodes are counted separately, so "<p>a</p><p>b</p>" is two words
+//
+// Postconditions:
+//   - Returns the number of words and the number of CJK characters
+func countWords(fragment string) (words, cjk int) {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return 0, 0
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			inWord := false
+			for _, r := range n.Data {
+				switch {
+				case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+					cjk++
+					inWord = false
+				case unicode.IsLetter(r) || unicode.IsDigit(r):
+					if !inWord {
+						words++
+					}
+					inWord = true
+				case unicode.IsSpace(r):
+					inWord = false
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return words, cjk
+}
+
+// jsonLDArticle holds the fields of a schema.org Article read from JSON-LD.
+type jsonLDArticle struct {
+	Type          jsonLDStrings   `json:"@type"`
+	Graph         []jsonLDArticle `json:"@graph"`
+	Headline      string          `json:"headline"`
+	Author        json.RawMessage `json:"author"`
+	DatePublished string          `json:"datePublished"`
+}
+
+// jsonLDStrings decodes a JSON-LD value that may be a string or an array
+// of strings.
+type jsonLDStrings []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *jsonLDStrings) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*v = jsonLDStrings{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*v = many
+	return nil
+}
+
+// isArticle reports whether a is one of articleTypes.
+func (a *jsonLDArticle) isArticle() bool {
+	return slices.ContainsFunc(a.Type, func(t string) bool {
+		return slices.Contains(articleTypes, t)
+	})
+}
+
+// authorName returns the first author name, which JSON-LD may give as a
+// string, a Person object, or an array of either.
+func (a *jsonLDArticle) authorName() string {
+	var name string
+	if json.Unmarshal(a.Author, &name) == nil {
+		return collapseText(name)
+	}
+	var person struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(a.Author, &person) == nil {
+		return collapseText(person.Name)
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(a.Author, &list) == nil && len(list) > 0 {
+		first := jsonLDArticle{Author: list[0]}
+		return first.authorName()
+	}
+	return ""
+}
+
+// findJSONLDArticle returns the first Article, NewsArticle, or BlogPosting
+// in the <script type="application/ld+json"> blocks of doc.
+//
+// Invariants:
+//   - Blocks that are not valid JSON are skipped
+//   - Top-level arrays and @graph arrays are searched
+//
+// Postconditions:
+//   - ok is false if no article is found
+func findJSONLDArticle(doc *html.Node) (article jsonLDArticle, ok bool) {
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "script" &&
+			strings.EqualFold(strings.TrimSpace(getAttr(n, "type")), "application/ld+json") {
+			article, ok = parseJSONLDArticle(getTextContent(n))
+			if ok {
+				return true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(doc)
+	return article, ok
+}
+
+// parseJSONLDArticle returns the first article described by a JSON-LD block.
+func parseJSONLDArticle(data string) (jsonLDArticle, bool) {
+	var items []jsonLDArticle
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		var item jsonLDArticle
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return jsonLDArticle{}, false
+		}
+		items = []jsonLDArticle{item}
+	}
+	for _, item := range items {
+		if item.isArticle() {
+			return item, true
+		}
+		for _, node := range item.Graph {
+			if node.isArticle() {
+				return node, true
+			}
+		}
+	}
+	return jsonLDArticle{}, false
+}
+
+// cleanTitle removes a site-name segment such as "| Site Name" from title.
+//
+// Preconditions:
+//   - h1 is the text of the page's first <h1>, or empty
+//
+// Invariants:
+//   - Only the first separator in titleSeparators found in title is used
+//
+// Postconditions:
+//   - If title or a run of its segments matches h1, that text is returned,
+//     so titles that legitimately contain separators are kept whole
+//   - Otherwise the shorter end segment, usually the site name, is dropped;
+//     titles split only by ":" are returned unchanged, since colons
+//     usually introduce subtitles
+func cleanTitle(title, h1 string) string {
+	if h1 != "" && strings.EqualFold(title, h1) {
+		return title
+	}
+	for _, sep := range titleSeparators {
+		segments := strings.Split(title, sep)
+		if len(segments) < 2 {
+			continue
+		}
+		for i := range segments {
+			segments[i] = strings.TrimSpace(segments[i])
+		}
+		if h1 != "" {
+			for i := 1; i < len(segments); i++ {
+				for _, candidate := range []string{
+					strings.Join(segments[:i], sep),
+					strings.Join(segments[i:], sep),
+				} {
+					if strings.EqualFold(strings.TrimSpace(candidate), h1) {
+						return strings.TrimSpace(candidate)
+					}
+				}
+			}
+		}
+		if strings.TrimSpace(sep) == ":" {
+			return title
+		}
+		first, last := segments[0], segments[len(segments)-1]
+		if len(last) > len(first) {
+			return strings.Join(segments[1:], sep)
+		}
+		return strings.Join(segments[:len(segments)-1], sep)
+	}
+	return title
+}
+
+// findByline returns the author named in doc, or an empty string.
+//
+// Postconditions:
+//   - <meta name="author"> takes precedence over elements in the body
+//   - Whitespace in the result is collapsed
+//   - Byline elements are cleaned by bylineText
+func findByline(doc *html.Node) string {
+	var meta, element string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "meta" && strings.EqualFold(getAttr(n, "name"), "author"):
+				if meta == "" {
+					meta = collapseText(getAttr(n, "content"))
+				}
+			case element == "" && (getAttr(n, "rel") == "author" ||
+				bylinePattern.MatchString(getAttr(n, "class")+" "+getAttr(n, "id"))):
+				element = bylineText(n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if meta != "" {
+		return meta
+	}
+	return element
+}
+
+// bylineText returns the author named by a byline element.
+//
+// Postconditions:
+//   - If n contains an hCard formatted name (class "fn" or "p-name"),
+//     only that name is used
+//   - A leading "By" and a trailing date are removed
+func bylineText(n *html.Node) string {
+	text := getTextContent(n)
+	if fn := findHCardName(n); fn != nil {
+		text = getTextContent(fn)
+	}
+	text = bylinePrefix.ReplaceAllString(collapseText(text), "")
+	return strings.Trim(bylineDate.ReplaceAllString(text, ""), " ,|·•–—-")
+}
+
+// findHCardName returns the first element in n's subtree, including n,
+// whose class marks an hCard formatted name, or nil.
+func findHCardName(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && hCardNamePattern.MatchString(getAttr(n, "class")) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findHCardName(c); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findCanonicalURL returns the canonical URL declared in doc, or an
+// empty string.
+//
+// Postconditions:
+//   - <link rel="canonical"> takes precedence over <meta property="og:url">
+func findCanonicalURL(doc *html.Node) string {
+	var canonical, ogURL string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "link" && canonical == "" &&
+				slices.ContainsFunc(strings.Fields(getAttr(n, "rel")), func(rel string) bool {
+					return strings.EqualFold(rel, "canonical")
+				}):
+				canonical = strings.TrimSpace(getAttr(n, "href"))
+			case n.Data == "meta" && ogURL == "" && strings.EqualFold(getAttr(n, "property"), "og:url"):
+				ogURL = strings.TrimSpace(getAttr(n, "content"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if canonical != "" {
+		return canonical
+	}
+	return ogURL
+}
+
+// findPublished returns the publication date declared in doc as written,
+// or an empty string.
+//
+// Postconditions:
+//   - <meta property="article:published_time"> takes precedence over
+//     <meta name="date">, which takes precedence over <time> elements
+//   - A <time> element is used only if it has a datetime attribute and
+//     a date-like class or the pubdate attribute
+func findPublished(doc *html.Node) string {
+	var articleMeta, dateMeta, timeElement string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "meta" && articleMeta == "" && strings.EqualFold(getAttr(n, "property"), "article:published_time"):
+				articleMeta = strings.TrimSpace(getAttr(n, "content"))
+			case n.Data == "meta" && dateMeta == "" && strings.EqualFold(getAttr(n, "name"), "date"):
+				dateMeta = strings.TrimSpace(getAttr(n, "content"))
+			case n.Data == "time" && timeElement == "" &&
+				(publishedPattern.MatchString(getAttr(n, "class")) ||
+					slices.ContainsFunc(n.Attr, func(a html.Attribute) bool { return a.Key == "pubdate" })):
+				timeElement = strings.TrimSpace(getAttr(n, "datetime"))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for _, published := range []string{articleMeta, dateMeta, timeElement} {
+		if published != "" {
+			return published
+		}
+	}
+	return ""
+}
+
+// parsePublished parses a publication date in one of publishedLayouts.
+// It returns the zero time if s matches none of them.
+func parsePublished(s string) time.Time {
+	for _, layout := range publishedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Lead image scoring weights. Loading hints are set by authors for the
+// hero image, so they outweigh a single size step; tiny images are
+// usually logos and icons and are penalized hardest.
+const (
+	leadImageHighPriority = 3  // fetchpriority="high"
+	leadImageLowPriority  = -2 // fetchpriority="low"
+	leadImageEager        = 1  // loading="eager"
+	leadImageLazy         = -1 // loading="lazy"
+	leadImageLarge        = 3  // at least leadImageLargeArea pixels
+	leadImageMedium       = 1  // at least leadImageMediumArea pixels
+	leadImageTiny         = -3 // below leadImageTinyArea pixels
+
+	leadImageLargeArea  = 400 * 400
+	leadImageMediumArea = 200 * 200
+	leadImageTinyArea   = 100 * 100
+)
+
+// ExtractLeadImage returns the URL of the main image of an HTML page.
+//
+// Preconditions:
+//   - rawHTML can be any string, including empty or invalid HTML
+//
+// Invariants:
+//   - <meta property="og:image"> is an explicit declaration and wins
+//   - Otherwise each <img> is scored by scoreLeadImage; ties go to the
+//     earlier image
+//
+// Postconditions:
+//   - Returns the URL as written, without resolving it
+//   - Returns an empty string if the page has no usable image
+func ExtractLeadImage(rawHTML string) string {
+	doc, err := html.Parse(strings.NewReader(rawHTML))
+	if err != nil {
+		return ""
+	}
+
+	var ogImage, best string
+	bestScore := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "meta" && ogImage == "" && strings.EqualFold(getAttr(n, "property"), "og:image"):
+				ogImage = strings.TrimSpace(getAttr(n, "content"))
+			case n.Data == "img":
+				src := strings.TrimSpace(getAttr(n, "src"))
+				if src == "" {
+					src = strings.TrimSpace(getAttr(n, "data-src"))
+				}
+				if score := scoreLeadImage(n); src != "" && (best == "" || score > bestScore) {
+					best, bestScore = src, score
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if ogImage != "" {
+		return ogImage
+	}
+	return best
+}
+
+// scoreLeadImage rates how likely an <img> is to be the hero image.
+//
+// Postconditions:
+//   - fetchpriority and loading hints add or subtract their weights
+//   - The area from the width and height attributes adds a size weight;
+//     images without both dimensions get no size weight
+func scoreLeadImage(n *html.Node) int {
+	score := 0
+	switch strings.ToLower(getAttr(n, "fetchpriority")) {
+	case "high":
+		score += leadImageHighPriority
+	case "low":
+		score += leadImageLowPriority
+	}
+	switch strings.ToLower(getAttr(n, "loading")) {
+	case "eager":
+		score += leadImageEager
+	case "lazy":
+		score += leadImageLazy
+	}
+
+	width, errW := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(getAttr(n, "width")), "px"))
+	height, errH := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(getAttr(n, "height")), "px"))
+	if errW == nil && errH == nil {
+		switch area := width * height; {
+		case area >= leadImageLargeArea:
+			score += leadImageLarge
+		case area >= leadImageMediumArea:
+			score += leadImageMedium
+		case area < leadImageTinyArea:
+			score += leadImageTiny
+		}
+	}
+	return score
+}
+
+// collapseText trims s and collapses internal whitespace to single spaces.
+func collapseText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		name          string
+		html          string
+		wantTitle     string
+		wantByline    string
+		wantPublished string
+		wantContent   string
+	}{
+		{
+			name: "title, byline meta, and content",
+			html: `<html><head><title>Post Title</title><meta name="author" content="Jane Doe"></head><body>
+				<nav><a href="/">Home</a></nav>
+				<article><h1>Post Title</h1><p>Body text, with prose.</p></article>
+			</body></html>`,
+			wantTitle:   "Post Title",
+			wantByline:  "Jane Doe",
+			wantContent: "Body text",
+		},
+		{
+			name: "byline from element class",
+			html: `<html><head><title>Post</title></head><body>
+				<article><p class="byline">  John
+					Smith </p><p>Body text.</p></article>
+			</body></html>`,
+			wantTitle:   "Post",
+			wantByline:  "John Smith",
+			wantContent: "Body text",
+		},
+		{
+			name: "byline from hCard author",
+			html: `<html><head><title>Post</title></head><body>
+				<article><p class="byline">By <span class="author vcard"><a class="url fn n" href="/jane">Jane Doe</a></span>
+					on <time datetime="2026-03-01">March 1, 2026</time></p><p>Body text.</p></article>
+			</body></html>`,
+			wantTitle:   "Post",
+			wantByline:  "Jane Doe",
+			wantContent: "Body text",
+		},
+		{
+			name: "byline from address rel author",
+			html: `<html><head><title>Post</title></head><body>
+				<article><address rel="author">Written by John Smith | 2026-03-01</address><p>Body text.</p></article>
+			</body></html>`,
+			wantTitle:   "Post",
+			wantByline:  "John Smith",
+			wantContent: "Body text",
+		},
+		{
+			name: "byline keeps names that start with By",
+			html: `<html><head><title>Post</title></head><body>
+				<article><span class="author">Byron Kay, 1 May 2026</span><p>Body text.</p></article>
+			</body></html>`,
+			wantTitle:   "Post",
+			wantByline:  "Byron Kay",
+			wantContent: "Body text",
+		},
+		{
+			name:        "title falls back to h1",
+			html:        `<html><body><article><h1>Heading</h1><p>Body text.</p></article></body></html>`,
+			wantTitle:   "Heading",
+			wantContent: "Body text",
+		},
+		{
+			name: "JSON-LD article metadata takes precedence",
+			html: `<html><head><title>Site | Other</title>
+				<meta name="author" content="Meta Author">
+				<script type="application/ld+json">{"@context":"https://schema.org","@type":"NewsArticle",
+					"headline":"Real Headline","author":{"@type":"Person","name":"Jane Doe"},
+					"datePublished":"2026-03-01T09:00:00Z"}</script></head><body>
+				<article><p>Body text, with prose.</p></article>
+			</body></html>`,
+			wantTitle:     "Real Headline",
+			wantByline:    "Jane Doe",
+			wantPublished: "2026-03-01T09:00:00Z",
+			wantContent:   "Body text",
+		},
+		{
+			name: "JSON-LD in @graph with author array",
+			html: `<html><head><title>Fallback</title>
+				<script type="application/ld+json">{"@graph":[{"@type":"WebSite","name":"Site"},
+					{"@type":["BlogPosting"],"headline":"Graph Post","author":["A. Writer","B. Writer"]}]}</script>
+				</head><body><p>Text</p></body></html>`,
+			wantTitle:  "Graph Post",
+			wantByline: "A. Writer",
+		},
+		{
+			name: "non-article and invalid JSON-LD fall back to heuristics",
+			html: `<html><head><title>Page Title</title>
+				<script type="application/ld+json">{"@type":"Organization","name":"Org"}</script>
+				<script type="application/ld+json">{not json</script></head>
+				<body><p rel="author">Someone</p></body></html>`,
+			wantTitle:  "Page Title",
+			wantByline: "Someone",
+		},
+		{
+			name:      "title is not cleaned by default",
+			html:      `<html><head><title>Post Title | Site</title></head><body><p>Text</p></body></html>`,
+			wantTitle: "Post Title | Site",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractArticle(tt.html)
+
+			if got.Title != tt.wantTitle {
+				t.Errorf("ExtractArticle() Title = %q, want %q", got.Title, tt.wantTitle)
+			}
+			if got.Byline != tt.wantByline {
+				t.Errorf("ExtractArticle() Byline = %q, want %q", got.Byline, tt.wantByline)
+			}
+			if got.Published != tt.wantPublished {
+				t.Errorf("ExtractArticle() Published = %q, want %q", got.Published, tt.wantPublished)
+			}
+			if !strings.Contains(got.Content, tt.wantContent) {
+				t.Errorf("ExtractArticle() Content should contain %q, got:\n%s", tt.wantContent, got.Content)
+			}
+		})
+	}
+}
+
+func TestExtractArticle_CanonicalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "canonical wins over og:url",
+			html: `<html><head><meta property="og:url" content="https://example.com/og">
+				<link rel="canonical" href="https://example.com/canonical"></head><body><p>Text</p></body></html>`,
+			want: "https://example.com/canonical",
+		},
+		{
+			name: "og:url only",
+			html: `<html><head><meta property="og:url" content=" https://example.com/og "></head><body><p>Text</p></body></html>`,
+			want: "https://example.com/og",
+		},
+		{
+			name: "absent",
+			html: `<html><head><link rel="stylesheet" href="/s.css"></head><body><p>Text</p></body></html>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractArticle(tt.html).CanonicalURL; got != tt.want {
+				t.Errorf("ExtractArticle() CanonicalURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArticle_Published(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		want     string
+		wantTime time.Time
+	}{
+		{
+			name: "article:published_time meta",
+			html: `<html><head><meta property="article:published_time" content="2026-03-01T09:30:00+09:00">
+				<meta name="date" content="2020-01-01"></head><body><p>Text</p></body></html>`,
+			want:     "2026-03-01T09:30:00+09:00",
+			wantTime: time.Date(2026, 3, 1, 0, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "date meta",
+			html:     `<html><head><meta name="date" content="2026-03-01"></head><body><p>Text</p></body></html>`,
+			want:     "2026-03-01",
+			wantTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "time element with date class",
+			html: `<html><body><article><time datetime="2025-12-24T18:00">Updated</time>
+				<time class="entry-date published" datetime="2025-12-01T08:00:00Z">Dec 1</time><p>Text</p></article></body></html>`,
+			want:     "2025-12-01T08:00:00Z",
+			wantTime: time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "JSON-LD wins over meta",
+			html: `<html><head><meta property="article:published_time" content="2020-01-01">
+				<script type="application/ld+json">{"@type":"Article","datePublished":"2026-03-01"}</script></head><body><p>Text</p></body></html>`,
+			want:     "2026-03-01",
+			wantTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "unparseable date keeps the raw string",
+			html: `<html><head><meta name="date" content="early spring"></head><body><p>Text</p></body></html>`,
+			want: "early spring",
+		},
+		{
+			name: "absent",
+			html: `<html><body><time datetime="2026-03-01">Today</time><p>Text</p></body></html>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractArticle(tt.html)
+			if got.Published != tt.want {
+				t.Errorf("ExtractArticle() Published = %q, want %q", got.Published, tt.want)
+			}
+			if !got.PublishedTime.Equal(tt.wantTime) {
+				t.Errorf("ExtractArticle() PublishedTime = %v, want %v", got.PublishedTime, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestExtractArticle_ReadingTime(t *testing.T) {
+	english := "<html><body><article><p>" + strings.Repeat("The quick brown fox jumps. ", 80) +
+		"</p><p>" + strings.Repeat("Over the lazy dog — again. ", 40) + "</p></article></body></html>"
+	japanese := "<html><body><article><p>" + strings.Repeat("吾輩は猫である。名前はまだ無い。", 50) +
+		"</p><p>HTMLとCSSを学ぶ。</p></article></body></html>"
+
+	tests := []struct {
+		name          string
+		html          string
+		cfg           ScoringConfig
+		wantWordCount int
+		wantReading   time.Duration
+	}{
+		{
+			name:          "english at the default speed",
+			html:          english,
+			cfg:           DefaultScoringConfig(),
+			wantWordCount: 600,
+			wantReading:   3 * time.Minute,
+		},
+		{
+			name:          "english at a configured speed",
+			html:          english,
+			cfg:           ScoringConfig{WordsPerMinute: 300},
+			wantWordCount: 600,
+			wantReading:   2 * time.Minute,
+		},
+		{
+			name: "japanese counted per character",
+			html: japanese,
+			cfg:  DefaultScoringConfig(),
+			// 700 kanji and kana, then 4 more and 2 words in the last paragraph
+			wantWordCount: 706,
+			wantReading:   time.Duration((704.0/500 + 2.0/200) * float64(time.Minute)).Round(time.Second),
+		},
+		{
+			name:          "empty content",
+			html:          "",
+			cfg:           DefaultScoringConfig(),
+			wantWordCount: 0,
+			wantReading:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractArticleWithConfig(tt.html, tt.cfg)
+			if got.WordCount != tt.wantWordCount {
+				t.Errorf("ExtractArticleWithConfig() WordCount = %d, want %d", got.WordCount, tt.wantWordCount)
+			}
+			if got.ReadingTime != tt.wantReading {
+				t.Errorf("ExtractArticleWithConfig() ReadingTime = %v, want %v", got.ReadingTime, tt.wantReading)
+			}
+		})
+	}
+}
+
+func TestExtractLeadImage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "large lazy hero beats small eager logo",
+			html: `<html><body>
+				<header><img src="/logo.png" width="120" height="40" loading="eager"></header>
+				<article><img src="/hero.jpg" width="1200" height="600" loading="lazy"><p>Text</p></article>
+			</body></html>`,
+			want: "/hero.jpg",
+		},
+		{
+			name: "fetchpriority high wins among equal sizes",
+			html: `<html><body>
+				<img src="/a.jpg" width="800" height="400">
+				<img src="/b.jpg" width="800" height="400" fetchpriority="high">
+			</body></html>`,
+			want: "/b.jpg",
+		},
+		{
+			name: "og:image takes precedence",
+			html: `<html><head><meta property="og:image" content="https://example.com/og.jpg"></head><body>
+				<img src="/hero.jpg" width="1200" height="600" fetchpriority="high">
+			</body></html>`,
+			want: "https://example.com/og.jpg",
+		},
+		{
+			name: "lazy image with data-src only",
+			html: `<html><body><img data-src="/lazy.jpg" loading="lazy"></body></html>`,
+			want: "/lazy.jpg",
+		},
+		{
+			name: "no image",
+			html: `<html><body><p>Text</p></body></html>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractLeadImage(tt.html); got != tt.want {
+				t.Errorf("ExtractLeadImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		h1    string
+		want  string
+	}{
+		{
+			name:  "site name suffix after pipe",
+			title: "How to Write Go | Example Blog",
+			want:  "How to Write Go",
+		},
+		{
+			name:  "site name prefix before dash",
+			title: "Blog - How to Write Go",
+			want:  "How to Write Go",
+		},
+		{
+			name:  "em dash separator",
+			title: "How to Write Go — Blog",
+			want:  "How to Write Go",
+		},
+		{
+			name:  "segment matching h1 is preferred over longer segment",
+			title: "Go | The Example Engineering Blog",
+			h1:    "Go",
+			want:  "Go",
+		},
+		{
+			name:  "separator inside the h1 is kept",
+			title: "Part 1 - Getting Started - Blog",
+			h1:    "Part 1 - Getting Started",
+			want:  "Part 1 - Getting Started",
+		},
+		{
+			name:  "title equal to h1 is kept whole",
+			title: "Go: The Good Parts",
+			h1:    "Go: The Good Parts",
+			want:  "Go: The Good Parts",
+		},
+		{
+			name:  "colon without h1 match is kept whole",
+			title: "Go: The Good Parts",
+			want:  "Go: The Good Parts",
+		},
+		{
+			name:  "colon site prefix removed when h1 matches",
+			title: "Blog: Go Tips",
+			h1:    "Go Tips",
+			want:  "Go Tips",
+		},
+		{
+			name:  "no separator",
+			title: "Plain Title",
+			want:  "Plain Title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanTitle(tt.title, tt.h1); got != tt.want {
+				t.Errorf("cleanTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArticleWithConfig_CleanTitle(t *testing.T) {
+	cfg := DefaultScoringConfig()
+	cfg.CleanTitle = true
+	got := ExtractArticleWithConfig(`<html><head><title>Example Site | Post Title Here</title></head>
+		<body><article><h1>Post Title Here</h1><p>Text</p></article></body></html>`, cfg)
+
+	if want := "Post Title Here"; got.Title != want {
+		t.Errorf("ExtractArticleWithConfig() Title = %q, want %q", got.Title, want)
+	}
+}
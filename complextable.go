@@ -0,0 +1,179 @@
+// Package main provides HTML passthrough for complex tables.
+//
+// This file keeps tables that a pipe table cannot represent, such as
+//
+//	<table>
+//	<tr><td rowspan="2">A</td><td>B</td></tr>
+//	<tr><td>C</td></tr>
+//	</table>
+//
+// as sanitized HTML when Options.ComplexTableAsHTML is set, since
+// Markdown renderers display HTML blocks unchanged.
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// reTableTagAny matches any opening or closing tag inside a table and
+	// captures the slash, the tag name, and the attribute text.
+	reTableTagAny = regexp.MustCompile(`(?i)<(/?)([a-z][a-z0-9]*)\b([^>]*)>`)
+
+	// reCellBlock matches block content that a pipe table cell cannot hold.
+	reCellBlock = regexp.MustCompile(`(?i)<(?:p|ul|ol)\b`)
+
+	reCellTag = regexp.MustCompile(`(?i)<t[dh]\b([^>]*)>`)
+	reTHead   = regexp.MustCompile(`(?is)<thead\b[^>]*>(.*?)</thead>`)
+	reTrOpen  = regexp.MustCompile(`(?i)<tr\b`)
+	reNewline = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// complexTableTags lists the tags kept in a complex table. Any other tag
+// is removed and its content kept.
+var complexTableTags = map[string]bool{
+	"table": true, "caption": true, "colgroup": true, "col": true,
+	"thead": true, "tbody": true, "tfoot": true, "tr": true, "th": true, "td": true,
+	"p": true, "br": true, "ul": true, "ol": true, "li": true,
+	"a": true, "strong": true, "b": true, "em": true, "i": true,
+	"code": true, "sub": true, "sup": true,
+}
+
+// complexTableAttrs lists the attributes kept on the tags of a complex table.
+var complexTableAttrs = []string{"rowspan", "colspan", "scope", "href"}
+
+// safeHrefSchemes lists the URL schemes allowed in the href of a complex
+// table. Relative URLs, which have no scheme, are also allowed.
+var safeHrefSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// preserveComplexTables replaces each complex top-level table with
+// sanitized HTML that survives the rest of the pipeline.
+//
+// Preconditions:
+//   - s is HTML before block conversion, so nested <p> and lists are intact
+//
+// Invariants:
+//   - Simple tables are left for convertTables
+//   - Tables nested in a complex table are kept inside it
+//
+// Postconditions:
+//   - A complex table becomes an HTML block surrounded by blank lines,
+//     written with escape placeholders and without blank lines inside it
+func preserveComplexTables(s string) string {
+	var sb strings.Builder
+	last, start, depth := 0, 0, 0
+	for _, loc := range reTableTag.FindAllStringSubmatchIndex(s, -1) {
+		if s[loc[2]:loc[3]] == "" {
+			if depth == 0 {
+				start = loc[0]
+			}
+			depth++
+			continue
+		}
+		if depth == 0 {
+			continue
+		}
+		depth--
+		if depth > 0 {
+			continue
+		}
+		table := s[start:loc[1]]
+		if !isComplexTable(table) {
+			continue
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString("\n\n" + sanitizeTable(table) + "\n\n")
+		last = loc[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// isComplexTable reports whether a table has a cell spanning rows, a cell
+// holding paragraphs or lists, or more than one header row.
+func isComplexTable(table string) bool {
+	for _, m := range reCellTag.FindAllStringSubmatch(table, -1) {
+		if v, ok := tagAttr(m[1], "rowspan"); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err != nil || n != 1 {
+				return true
+			}
+		}
+	}
+	if reCellBlock.MatchString(table) {
+		return true
+	}
+	for _, m := range reTHead.FindAllStringSubmatch(table, -1) {
+		if len(reTrOpen.FindAllStringIndex(m[1], -1)) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeTable rewrites a table as HTML that only uses complexTableTags
+// and complexTableAttrs.
+//
+// Invariants:
+//   - Text and entities are kept as written; & is written as escAmp so
+//     that cleanup does not decode entities into markup
+//   - An href is dropped unless isSafeHref accepts it
+//
+// Postconditions:
+//   - Tags are lowercased and written with escape placeholders
+//   - Whitespace around line breaks is collapsed to a single newline
+func sanitizeTable(table string) string {
+	table = reNewline.ReplaceAllString(strings.TrimSpace(table), "\n")
+	var sb strings.Builder
+	last := 0
+	for _, m := range reTableTagAny.FindAllStringSubmatchIndex(table, -1) {
+		sb.WriteString(strings.ReplaceAll(table[last:m[0]], "&", escAmp))
+		last = m[1]
+		name := strings.ToLower(table[m[4]:m[5]])
+		if !complexTableTags[name] {
+			continue
+		}
+		sb.WriteString(escLT + table[m[2]:m[3]] + name)
+		if m[3] == m[2] {
+			attrs := table[m[6]:m[7]]
+			for _, attr := range complexTableAttrs {
+				v, ok := tagAttr(attrs, attr)
+				if !ok || attr == "href" && !isSafeHref(v) {
+					continue
+				}
+				v = strings.ReplaceAll(v, "&", escAmp)
+				v = strings.ReplaceAll(v, `"`, escAmp+"quot;")
+				sb.WriteString(" " + attr + `="` + v + `"`)
+			}
+		}
+		sb.WriteString(escGT)
+	}
+	sb.WriteString(strings.ReplaceAll(table[last:], "&", escAmp))
+	return sb.String()
+}
+
+// isSafeHref reports whether href is relative or uses a scheme in
+// safeHrefSchemes.
+//
+// Invariants:
+//   - The scheme is read as a browser reads it, after decoding entities
+//     and removing whitespace and control characters, so that
+//     "&#106;avascript:" or "java&#x09;script:" is not taken for a
+//     relative URL
+func isSafeHref(href string) bool {
+	href = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(href))
+	i := strings.IndexAny(href, ":/?#")
+	if i < 0 || href[i] != ':' {
+		return true
+	}
+	return safeHrefSchemes[strings.ToLower(href[:i])]
+}
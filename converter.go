@@ -25,22 +25,49 @@
 //
 // Headings are processed from h6 to h1 because:
 //   - Prevents <h1><h2>nested</h2></h1> from matching h1 first and losing h2
+//
+// Horizontal rules are processed before blockquotes because:
+//   - An <hr> inside a blockquote must become a quoted "> ---" line;
+//     converting it afterward would place a bare "---" outside the quote
 package main
 
 import (
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Escape sequences for preserving angle brackets in inline code.
+// Escape sequences for preserving characters that cleanup would otherwise remove.
 // These placeholder strings survive HTML tag cleanup and are restored afterward.
-// Using null bytes ensures these sequences never appear in normal HTML content.
+// Their null bytes cannot appear in the pipeline otherwise, since convert
+// replaces every NUL in the input with U+FFFD first.
 const (
-	escLT = "\x00LT\x00" // Placeholder for < in inline code
-	escGT = "\x00GT\x00" // Placeholder for > in inline code
+	escLT         = "\x00LT\x00" // Placeholder for < in code and preserved HTML
+	escGT         = "\x00GT\x00" // Placeholder for > in code and preserved HTML
+	escIndent     = "\x00IN\x00" // Placeholder for one space of list item indentation
+	escCodeIndent = "\x00CI\x00" // Placeholder for the four-space indent of an indented code line
+	escAmp        = "\x00AM\x00" // Placeholder for & in preserved HTML, kept through entity decoding
+)
+
+// invisibleCharReplacer removes the invisible characters that are stripped
+// from text when Options.StripInvisibleChars is set: soft hyphens,
+// zero-width spaces, word joiners, and byte order marks. Zero-width joiners
+// are kept, since emoji sequences need them. Entities such as &shy; are
+// decoded to these characters before the replacer runs, so an escaped
+// &amp;shy; stays as the text "&shy;".
+var invisibleCharReplacer = strings.NewReplacer(
+	"\u00ad", "",
+	"\u200b", "",
+	"\u2060", "",
+	"\ufeff", "",
 )
 
+// nbsp is the U+00A0 non-breaking space emitted when Options.PreserveNBSP is set.
+const nbsp = "\u00a0"
+
 // Precompiled regex patterns for HTML element matching.
 // These are compiled once at package initialization for performance.
 //
@@ -53,70 +80,137 @@ const (
 // Multi-line elements (blockquote, pre, table, lists) use (?is).
 var (
 	reWhitespace   = regexp.MustCompile(`[ \t]+`)
-	reH1           = regexp.MustCompile(`(?i)<h1[^>]*>(.*?)</h1>`)
-	reH2           = regexp.MustCompile(`(?i)<h2[^>]*>(.*?)</h2>`)
-	reH3           = regexp.MustCompile(`(?i)<h3[^>]*>(.*?)</h3>`)
-	reH4           = regexp.MustCompile(`(?i)<h4[^>]*>(.*?)</h4>`)
-	reH5           = regexp.MustCompile(`(?i)<h5[^>]*>(.*?)</h5>`)
-	reH6           = regexp.MustCompile(`(?i)<h6[^>]*>(.*?)</h6>`)
+	reH1           = regexp.MustCompile(`(?i)<h1([^>]*)>(.*?)</h1>`)
+	reH2           = regexp.MustCompile(`(?i)<h2([^>]*)>(.*?)</h2>`)
+	reH3           = regexp.MustCompile(`(?i)<h3([^>]*)>(.*?)</h3>`)
+	reH4           = regexp.MustCompile(`(?i)<h4([^>]*)>(.*?)</h4>`)
+	reH5           = regexp.MustCompile(`(?i)<h5([^>]*)>(.*?)</h5>`)
+	reH6           = regexp.MustCompile(`(?i)<h6([^>]*)>(.*?)</h6>`)
 	reParagraph    = regexp.MustCompile(`(?i)<p[^>]*>(.*?)</p>`)
+	reDetails      = regexp.MustCompile(`(?is)<details\b[^>]*>(.*?)</details>`)
+	reHgroup       = regexp.MustCompile(`(?is)<hgroup\b[^>]*>(.*?)</hgroup>`)
+	reAnyHeading   = regexp.MustCompile(`(?is)<h[1-6]\b[^>]*>(.*?)</h[1-6]>`)
+	reHeadingLevel = regexp.MustCompile(`(?i)<h([1-6])\b`)
+	reFigure       = regexp.MustCompile(`(?is)<figure\b[^>]*>(.*?)</figure>`)
+	reAmpImg       = regexp.MustCompile(`(?is)<amp-(?:img|anim)\b([^>]*)>(?:.*?</amp-(?:img|anim)>)?`)
+	reAmpVideoTag  = regexp.MustCompile(`(?i)<(/?)amp-video\b`)
+	rePicture      = regexp.MustCompile(`(?is)<picture\b[^>]*>(.*?)</picture>`)
+	reFormControl  = regexp.MustCompile(`(?is)<select\b[^>]*>.*?</select>|<datalist\b[^>]*>.*?</datalist>`)
+	reSource       = regexp.MustCompile(`(?i)<source\b([^>]*)>`)
+	reFigcaption   = regexp.MustCompile(`(?is)<figcaption\b[^>]*>(.*?)</figcaption>`)
+	reSummary      = regexp.MustCompile(`(?is)<summary\b[^>]*>(.*?)</summary>`)
 	reBlockquote   = regexp.MustCompile(`(?is)<blockquote[^>]*>(.*?)</blockquote>`)
 	rePreCode      = regexp.MustCompile(`(?is)<pre[^>]*><code[^>]*>(.*?)</code></pre>`)
 	rePre          = regexp.MustCompile(`(?is)<pre[^>]*>(.*?)</pre>`)
 	reHr           = regexp.MustCompile(`(?i)<hr\s*/?>`)
-	reUl           = regexp.MustCompile(`(?is)<ul[^>]*>(.*?)</ul>`)
-	reOl           = regexp.MustCompile(`(?is)<ol[^>]*>(.*?)</ol>`)
-	reLi           = regexp.MustCompile(`(?is)<li[^>]*>(.*?)</li>`)
+	reListTag      = regexp.MustCompile(`(?i)<(/?)(ul|ol)\b([^>]*)>`)
+	reLi           = regexp.MustCompile(`(?is)<li([^>]*)>(.*?)</li>`)
 	rePTag         = regexp.MustCompile(`(?i)</?p[^>]*>`)
 	reTable        = regexp.MustCompile(`(?is)<table[^>]*>(.*?)</table>`)
 	reRow          = regexp.MustCompile(`(?is)<tr[^>]*>(.*?)</tr>`)
-	reTh           = regexp.MustCompile(`(?is)<th[^>]*>(.*?)</th>`)
-	reTd           = regexp.MustCompile(`(?is)<td[^>]*>(.*?)</td>`)
-	reLink         = regexp.MustCompile(`(?is)<a[^>]*href=["']([^"']*)["'][^>]*>(.*?)</a>`)
-	reImgSrcAlt    = regexp.MustCompile(`(?i)<img[^>]*src=["']([^"']*)["'][^>]*alt=["']([^"']*)["'][^>]*/?>`)
-	reImgAltSrc    = regexp.MustCompile(`(?i)<img[^>]*alt=["']([^"']*)["'][^>]*src=["']([^"']*)["'][^>]*/?>`)
-	reImgSrc       = regexp.MustCompile(`(?i)<img[^>]*src=["']([^"']*)["'][^>]*/?>`)
-	reBold         = regexp.MustCompile(`(?is)<(strong|b)[^>]*>(.*?)</(strong|b)>`)
-	reItalic       = regexp.MustCompile(`(?is)<(em|i)[^>]*>(.*?)</(em|i)>`)
+	reCell         = regexp.MustCompile(`(?is)<(th|td)\b([^>]*)>(.*?)</(?:th|td)>`)
+	reCol          = regexp.MustCompile(`(?i)<col\b([^>]*)>`)
+	reHighlightTbl = regexp.MustCompile(`(?i)\bhighlight(?:table)?\b`)
+	reLineNoCell   = regexp.MustCompile(`(?i)\b(?:linenos?|gutter|line-numbers?|blob-num)\b`)
+	reTableTag     = regexp.MustCompile(`(?i)<(/?)table\b[^>]*>`)
+	reLink         = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a>`)
+	reImg          = regexp.MustCompile(`(?i)<img\b([^>]*)>`)
+	reAttr         = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'<>` + "`" + `]+))`)
+	reAttrName     = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'<>` + "`" + `]+))?`)
+	reInput        = regexp.MustCompile(`(?i)<input\b([^>]*)>[ \t]*`)
+	reControlEnd   = regexp.MustCompile(`(?i)(</(?:button|output|label|legend)>)(<(?:button|output|label|legend)\b|[\p{L}\p{N}])`)
+	reControlStart = regexp.MustCompile(`(?i)([\p{L}\p{N}])(<(?:button|output|label|legend)\b)`)
+	reBold         = regexp.MustCompile(`(?is)<(strong|b)\b[^>]*>(.*?)</(strong|b)>`)
+	reBoldTag      = regexp.MustCompile(`(?i)</?(?:strong|b)\b[^>]*>`)
+	reItalic       = regexp.MustCompile(`(?is)<(em|i)\b[^>]*>(.*?)</(em|i)>`)
+	reItalicTag    = regexp.MustCompile(`(?i)</?(?:em|i)\b[^>]*>`)
+	reStrike       = regexp.MustCompile(`(?is)<(del|s|strike)\b[^>]*>(.*?)</(del|s|strike)>`)
+	reFormatTag    = regexp.MustCompile(`(?i)<(/?)(b|strong|i|em|s|strike|del)\b[^>]*>`)
+	reStrikeTag    = regexp.MustCompile(`(?i)</?(?:del|s|strike)\b[^>]*>`)
+	reStyledSpan   = regexp.MustCompile(`(?is)<span[^>]*\bstyle=["']([^"']*)["'][^>]*>(.*?)</span>`)
+	reMonoTag      = regexp.MustCompile(`(?i)<(/?)(?:kbd|tt)\b[^>]*>`)
+	reCodeLikeTag  = regexp.MustCompile(`(?i)</?(?:kbd|tt|code)\b[^>]*>`)
 	reInlineCode   = regexp.MustCompile(`(?is)<code[^>]*>(.*?)</code>`)
+	reAdjacentCode = regexp.MustCompile(`(?i)</code>(<code\b)`)
 	reBr           = regexp.MustCompile(`(?i)<br\s*/?>`)
+	reSpacedBr     = regexp.MustCompile(`(?i)\s*<br\s*/?>\s*`)
+	reDoubleBr     = regexp.MustCompile(`(?i)(?:<br\s*/?>[ \t\n]*){2,}`)
+	rePreStyled    = regexp.MustCompile(`(?i)<(div|span|p)\b([^>]*)>`)
+	reDivOpen      = regexp.MustCompile(`(?i)<div\b([^>]*)>`)
+	reAdmonition   = regexp.MustCompile(`(?i)<(div|aside|section)\b([^>]*)>`)
+	reAsideTag     = regexp.MustCompile(`(?i)<(/?)aside\b[^>]*>`)
+	reSectionTag   = regexp.MustCompile(`(?i)<(/?)section\b[^>]*>`)
+	reAdmonTitle   = regexp.MustCompile(`(?is)^\s*<(p|div|span|strong)\b([^>]*)>.*?</(?:p|div|span|strong)>`)
+	reAlertMarker  = regexp.MustCompile(`^> \[![A-Z]+\]$`)
+	reParaTag      = regexp.MustCompile(`(?i)<(/?)p\b[^>]*>`)
+	rePreOrTable   = regexp.MustCompile(`(?is)<pre[^>]*>.*?</pre>|<table[^>]*>.*?</table>`)
+	reTrailingBr   = regexp.MustCompile(`(?i)(?:\s*<br\s*/?>)+\s*(</(?:li|h[1-6]|td|th|p|blockquote)\s*>)`)
 	reHtmlTag      = regexp.MustCompile(`<[^>]*>`)
+	reComment      = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+	reQuote        = regexp.MustCompile(`(?is)<q\b([^>]*)>(.*?)</q>`)
+	reAbbr         = regexp.MustCompile(`(?is)<abbr\b([^>]*)>(.*?)</abbr>`)
+	reRuby         = regexp.MustCompile(`(?is)<ruby\b[^>]*>(.*?)</ruby>`)
+	reRubyRt       = regexp.MustCompile(`(?is)<rt\b[^>]*>(.*?)</rt>`)
+	reRubyRp       = regexp.MustCompile(`(?is)<rp\b[^>]*>.*?</rp>`)
+	reRubyBaseTag  = regexp.MustCompile(`(?i)</?(?:rb|rtc)\b[^>]*>`)
+	reHtmlTagName  = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
 	reMultiNewline = regexp.MustCompile(`\n{3,}`)
+	reHeadingNum   = regexp.MustCompile(`^\d{1,3}(?:\.\d{1,3})*[.)]\s+`)
 )
 
-// headingDefs defines the mapping from HTML heading levels to Markdown prefixes.
+// headingDefs defines the mapping from HTML heading tags to heading levels.
 // Processed from h6 to h1 to handle nested headings correctly:
 // e.g., <h1><h2>nested</h2></h1> - processing h6 first prevents h1 from
 // matching and losing the inner h2 content.
 var headingDefs = []struct {
-	re     *regexp.Regexp
-	prefix string
+	re    *regexp.Regexp
+	level int
 }{
-	{reH6, "###### "},
-	{reH5, "##### "},
-	{reH4, "#### "},
-	{reH3, "### "},
-	{reH2, "## "},
-	{reH1, "# "},
-}
-
-// htmlEntityReplacer decodes common HTML entities efficiently.
-// Using strings.NewReplacer is faster than map iteration with ReplaceAll.
-var htmlEntityReplacer = strings.NewReplacer(
-	"&lt;", "<",
-	"&gt;", ">",
-	"&amp;", "&",
-	"&quot;", "\"",
-	"&#39;", "'",
-	"&apos;", "'",
-	"&nbsp;", " ",
-)
+	{reH6, 6},
+	{reH5, 5},
+	{reH4, 4},
+	{reH3, 3},
+	{reH2, 2},
+	{reH1, 1},
+}
+
+// defaultImageKeepAttrs lists the <img> attributes kept by Options.ImagesAsHTML
+// when Options.ImageKeepAttrs is empty.
+var defaultImageKeepAttrs = []string{"loading", "decoding"}
+
+// defaultAdmonitionClasses maps class names to GFM alert types for
+// Options.Admonitions when Options.AdmonitionClasses is empty.
+var defaultAdmonitionClasses = map[string]string{
+	"note":      "NOTE",
+	"tip":       "TIP",
+	"important": "IMPORTANT",
+	"warning":   "WARNING",
+	"caution":   "CAUTION",
+}
+
+// htmlEntities maps the names of the entities decoded by
+// decodeHTMLEntities to their text. &nbsp; becomes a plain space, since
+// Options.PreserveNBSP decodes it earlier when it should be kept. &shy;
+// becomes U+00AD, which stripInvisibleChars then removes by default.
+var htmlEntities = map[string]string{
+	"lt": "<", "LT": "<",
+	"gt": ">", "GT": ">",
+	"amp": "&", "AMP": "&",
+	"quot": "\"", "QUOT": "\"",
+	"apos": "'",
+	"nbsp": " ",
+	"shy":  "\u00ad",
+}
+
+// reEntity matches a character reference with an optional semicolon:
+// a hexadecimal or decimal numeric reference, or a name from htmlEntities.
+var reEntity = regexp.MustCompile(`&(?:#[xX]([0-9a-fA-F]+)|#([0-9]+)|(lt|LT|gt|GT|amp|AMP|quot|QUOT|apos|nbsp|shy))(;?)`)
 
 // Convert transforms an HTML string into Markdown format.
 //
-// It processes block elements (headings, paragraphs, lists, tables, code blocks)
-// first, then inline elements (links, images, bold, italic, code), and finally
-// cleans up the output.
+// It processes block elements (headings, paragraphs, lists, tables, code
+// blocks) first, then inline elements (links, images, bold, italic,
+// strikethrough, code), and finally cleans up the output.
 //
 // Preconditions:
 //   - html can be any string, including empty string
@@ -132,33 +226,314 @@ var htmlEntityReplacer = strings.NewReplacer(
 //   - Unrecognized HTML tags are removed from output
 //   - Multiple consecutive newlines are normalized to at most two
 func Convert(html string) string {
+	return ConvertWithOptions(html, DefaultOptions())
+}
+
+// ConvertBytes transforms HTML bytes into Markdown bytes for callers that
+// read and write []byte, such as HTTP handlers.
+//
+// Preconditions:
+//   - html may be nil or empty
+//
+// Postconditions:
+//   - The result equals []byte(Convert(string(html)))
+//   - The result does not share memory with html
+func ConvertBytes(html []byte) []byte {
+	return []byte(Convert(string(html)))
+}
+
+// ConvertWithOptions transforms an HTML string into Markdown format using opts.
+//
+// Preconditions:
+//   - html can be any string, including empty string
+//   - opts may be the zero value
+//
+// Invariants:
+//   - Processing order is the same as Convert
+//   - opts is not modified
+//
+// Postconditions:
+//   - Returns trimmed Markdown string
+//   - With DefaultOptions(), the result is identical to Convert
+func ConvertWithOptions(html string, opts Options) string {
+	return convert(html, &opts, nil)
+}
+
+// ConvertReadable extracts the main content of an HTML page and converts
+// it to Markdown, producing reader-mode output.
+//
+// Preconditions:
+//   - rawHTML can be any string, including a fragment without <body>
+//
+// Invariants:
+//   - Extraction uses DefaultScoringConfig
+//
+// Postconditions:
+//   - Unlike Convert, extraction also runs on input without a <body> tag
+//   - If no content candidate is found, the whole input is converted
+func ConvertReadable(rawHTML string) string {
+	return ConvertReadableWithOptions(rawHTML, DefaultOptions())
+}
+
+// ConvertReadableWithOptions is ConvertReadable using opts for conversion.
+//
+// Postconditions:
+//   - With DefaultOptions(), the result is identical to ConvertReadable
+func ConvertReadableWithOptions(rawHTML string, opts Options) string {
+	cfg := DefaultScoringConfig()
+	if content, ok := extractDocument(rawHTML, &cfg); ok {
+		rawHTML = content
+	}
+	return convert(rawHTML, &opts, nil)
+}
+
+// convert runs the conversion pipeline shared by all public entry points.
+//
+// Preconditions:
+//   - opts is non-nil
+//   - report may be nil when the caller does not need diagnostics
+//
+// Postconditions:
+//   - Returns trimmed Markdown string
+//   - If report is non-nil, it is populated with warnings and unconverted tags
+func convert(html string, opts *Options, report *ConversionReport) string {
+	// NUL bytes would forge escape placeholders, so they are replaced as
+	// the HTML parser does
+	html = strings.ReplaceAll(html, "\x00", "\uFFFD")
+
+	// Without a tag there is nothing to convert, so the passes are skipped
+	if strings.IndexByte(html, '<') < 0 {
+		return finishMarkdown(convertPlainText(html, opts), opts, report)
+	}
+	return finishMarkdown(convertTags(html, opts, report), opts, report)
+}
+
+// convertPlainText prepares input without any tags for finishMarkdown.
+//
+// Invariants:
+//   - The result equals that of convertTags for the same input, since
+//     every pass of convertTags other than these only changes tags
+func convertPlainText(html string, opts *Options) string {
+	if opts.PreserveNBSP {
+		html = strings.ReplaceAll(html, "&nbsp;", nbsp)
+	}
+	html = normalizeWhitespace(html)
+	if opts.Autolink {
+		html = autolinkText(html, opts)
+	}
+	return html
+}
+
+// convertTags runs the passes that convert HTML tags to Markdown.
+//
+// Preconditions:
+//   - NUL bytes in html have been replaced
+//
+// Postconditions:
+//   - Returns Markdown that still contains escape placeholders, entities,
+//     and the tags no pass converted, for finishMarkdown
+func convertTags(html string, opts *Options, report *ConversionReport) string {
 	// Extract main content first
 	html = ExtractContent(html)
 
+	// Restrict conversion to allowed tags
+	html = stripDisallowedTags(html, opts)
+
+	// Overlapping formatting tags would produce tangled markers
+	html = fixMisnestedTags(html)
+
+	// The TOC marker is a comment, so it must be found before comments are handled
+	if opts.GenerateTOC {
+		html = markTOC(html)
+	}
+
+	// Comments are handled before any pass can match tags inside them
+	html = convertComments(html, opts)
+
+	// Decode non-breaking spaces before normalization so they are not collapsed
+	if opts.PreserveNBSP {
+		html = strings.ReplaceAll(html, "&nbsp;", nbsp)
+	}
+
+	// Line-numbered code tables become <pre> before whitespace is collapsed
+	html = convertLineNumberTables(html)
+	html = convertPreStyled(html)
+
 	// Normalize whitespace and newlines
 	html = normalizeWhitespace(html)
 
+	// Break runs become paragraph breaks before trailing breaks are trimmed
+	if opts.DoubleBreakParagraph {
+		html = convertDoubleBreaks(html)
+	}
+
+	// Trailing <br> in blocks would leave stray hard breaks
+	html = trimTrailingBreaks(html)
+
+	// Footnotes must be collected before lists and links are converted
+	if opts.Footnotes {
+		html = convertFootnotes(html)
+	}
+
+	// Math is extracted before other passes can touch the TeX inside it
+	if opts.PreserveMath {
+		html = convertMath(html)
+	}
+
+	// Checkboxes become text before lists, so items read as task list items
+	if opts.TaskLists {
+		html = convertCheckboxes(html)
+	}
+
+	// Form controls are reduced to their readable text
+	html = convertFormControls(html)
+
+	// Separator divs become <hr> for convertHorizontalRules
+	if len(opts.SeparatorClasses) > 0 {
+		html = convertSeparators(html, opts)
+	}
+
+	// Admonitions become blockquotes for convertBlockquotes
+	if opts.Admonitions {
+		html = convertAdmonitions(html, opts)
+	}
+
+	// Section boundaries become paragraph breaks once admonitions are found
+	if opts.SectionSpacing {
+		html = reSectionTag.ReplaceAllString(html, "\n\n")
+	}
+
+	// Complex tables are kept as HTML before their cell content is converted
+	if opts.ComplexTableAsHTML {
+		html = preserveComplexTables(html)
+	}
+
+	// Bare URLs are found while links and code are still tags
+	if opts.Autolink {
+		html = autolinkURLs(html, opts)
+	}
+
+	// AMP pages use custom elements in place of <img> and <video>
+	html = convertAMPMedia(html)
+
+	// Pictures are reduced to one <img> before images are counted or linked
+	html = convertPictures(html)
+
+	report.recordElements(html)
+
 	// Process block elements first
-	html = convertHeadings(html)
+	html = convertDetails(html, opts)
+	html = convertHgroups(html)
+	html = convertFigures(html, opts)
+	html = convertHeadings(html, opts)
 	html = convertParagraphs(html)
-	html = convertBlockquotes(html)
-	html = convertCodeBlocks(html)
 	html = convertHorizontalRules(html)
-	html = convertLists(html)
-	html = convertTables(html)
+	html = convertBlockquotes(html)
+	html = convertCodeBlocks(html, opts)
+	html = convertLists(html, opts)
+	html = convertTables(html, opts, report)
 
 	// Process inline elements
-	html = convertLinks(html)
-	html = convertImages(html)
+	html = convertRuby(html, opts)
+	if opts.ExpandAbbreviations {
+		html = convertAbbreviations(html)
+	}
+	if opts.IncludeQuoteCitations {
+		html = convertQuotes(html)
+	}
+	html = convertLinks(html, opts)
+	html = convertImages(html, opts)
+	if opts.InferEmphasisFromStyle {
+		html = convertStyledSpans(html)
+	}
 	html = convertBold(html)
 	html = convertItalic(html)
+	html = convertStrikethrough(html)
+	html = convertKeyboard(html)
 	html = convertInlineCode(html)
 	html = convertLineBreaks(html)
 
-	// Clean up
+	report.recordUnconvertedTags(html)
+	return html
+}
+
+// finishMarkdown cleans up converted output and applies the options that
+// work on the final Markdown.
+//
+// Postconditions:
+//   - Remaining tags are stripped, placeholders restored, and entities decoded
+//   - The result is trimmed, then post-processed by CollapseWhitespace,
+//     GenerateTOC, MaxLength, and LineEnding
+func finishMarkdown(html string, opts *Options, report *ConversionReport) string {
 	html = cleanupOutput(html)
+	if opts.StripInvisibleChars {
+		html = stripInvisibleChars(html)
+	}
+
+	// Code indentation is restored after trimming so that an indented code
+	// block at the start of the document keeps its first indent
+	html = strings.TrimSpace(html)
+	if opts.CollapseWhitespace {
+		html = collapseProseWhitespace(html)
+	}
+	html = strings.ReplaceAll(html, escCodeIndent, "    ")
+
+	if opts.GenerateTOC {
+		html = insertTOC(html, opts)
+	}
+	if opts.MaxLength > 0 && opts.ReferenceLinks {
+		html = truncateWithDefinitions(html, opts.MaxLength)
+	} else if opts.MaxLength > 0 {
+		html = truncateMarkdown(html, opts.MaxLength)
+	}
+
+	eol, ok := opts.lineEnding()
+	if !ok {
+		report.addWarning("unrecognized line ending %q, using LF", opts.LineEnding)
+	}
+	if eol != LF {
+		html = strings.ReplaceAll(strings.ReplaceAll(html, "\r\n", "\n"), "\n", eol)
+	}
+	return html
+}
+
+// convertComments handles HTML comments according to opts.KeepComments.
+//
+// Preconditions:
+//   - opts is non-nil
+//
+// Invariants:
+//   - Comments are matched as a whole, so a > inside a comment does not
+//     end it early as it would for reHtmlTag
+//   - Kept comment text is escaped so later passes do not convert it
+//
+// Postconditions:
+//   - Strip removes every comment
+//   - HTMLPassthrough keeps each comment as <!-- text -->
+//   - MarkdownLink replaces each non-empty comment with a "[//]: # (text)"
+//     line surrounded by blank lines, with parentheses escaped
+func convertComments(s string, opts *Options) string {
+	return reComment.ReplaceAllStringFunc(s, func(match string) string {
+		text := reComment.FindStringSubmatch(match)[1]
+		switch opts.KeepComments {
+		case HTMLPassthrough:
+			return escLT + "!--" + escapeAngles(text) + "--" + escGT
+		case MarkdownLink:
+			text = strings.Join(strings.Fields(text), " ")
+			if text == "" {
+				return ""
+			}
+			text = strings.NewReplacer("(", `\(`, ")", `\)`).Replace(text)
+			return "\n\n[//]: # (" + escapeAngles(text) + ")\n\n"
+		}
+		return ""
+	})
+}
 
-	return strings.TrimSpace(html)
+// escapeAngles replaces < and > with placeholders that survive cleanup.
+func escapeAngles(s string) string {
+	s = strings.ReplaceAll(s, "<", escLT)
+	return strings.ReplaceAll(s, ">", escGT)
 }
 
 // normalizeWhitespace collapses consecutive spaces and tabs into a single space.
@@ -169,37 +544,388 @@ func Convert(html string) string {
 // Invariants:
 //   - Only horizontal whitespace (spaces and tabs) is affected
 //   - Newlines are preserved
+//   - Content of <pre> blocks is left untouched, keeping code indentation
 //
 // Postconditions:
-//   - All sequences of spaces/tabs are replaced with a single space
+//   - All sequences of spaces/tabs outside <pre> are replaced with a single space
 func normalizeWhitespace(s string) string {
-	return reWhitespace.ReplaceAllString(s, " ")
+	var sb strings.Builder
+	sb.Grow(len(s))
+	last := 0
+	for _, loc := range rePre.FindAllStringIndex(s, -1) {
+		sb.WriteString(reWhitespace.ReplaceAllString(s[last:loc[0]], " "))
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(reWhitespace.ReplaceAllString(s[last:], " "))
+	return sb.String()
+}
+
+// convertDoubleBreaks turns runs of two or more <br> tags into paragraph
+// breaks, for pages that separate paragraphs with <br><br> instead of <p>.
+//
+// Invariants:
+//   - <pre> blocks and tables are left unchanged, since a blank line
+//     cannot appear inside a table row and code keeps its breaks
+//
+// Postconditions:
+//   - Each run, with the whitespace between its tags, becomes a blank line
+//   - A single <br> is left for convertLineBreaks
+func convertDoubleBreaks(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	last := 0
+	for _, loc := range rePreOrTable.FindAllStringIndex(s, -1) {
+		sb.WriteString(reDoubleBr.ReplaceAllString(s[last:loc[0]], "\n\n"))
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(reDoubleBr.ReplaceAllString(s[last:], "\n\n"))
+	return sb.String()
+}
+
+// trimTrailingBreaks removes <br> tags that end a block element.
+//
+// A hard break at the end of a list item, heading, table cell, paragraph,
+// or blockquote is meaningless and would leave "  \n" inside the block,
+// breaking lists and tables.
+//
+// Invariants:
+//   - <br> tags followed by more content in the block are unchanged
+//
+// Postconditions:
+//   - No <br> directly precedes </li>, </h1>-</h6>, </td>, </th>, </p>,
+//     or </blockquote>, ignoring whitespace
+func trimTrailingBreaks(s string) string {
+	return reTrailingBr.ReplaceAllString(s, "$1")
+}
+
+// stripDisallowedTags removes tags that opts does not allow to be converted.
+//
+// Preconditions:
+//   - opts is non-nil
+//
+// Invariants:
+//   - Text content of stripped tags is preserved
+//   - Comments and doctypes are left for cleanupOutput
+//
+// Postconditions:
+//   - If opts.AllowedTags is empty, s is returned unchanged
+//   - Otherwise only allowed opening and closing tags remain
+func stripDisallowedTags(s string, opts *Options) string {
+	if len(opts.AllowedTags) == 0 {
+		return s
+	}
+	return reHtmlTagName.ReplaceAllStringFunc(s, func(tag string) string {
+		if opts.allowsTag(reHtmlTagName.FindStringSubmatch(tag)[1]) {
+			return tag
+		}
+		return ""
+	})
+}
+
+// convertDetails preserves HTML <details> and <summary> tags around
+// Markdown content, since Markdown has no disclosure widget.
+//
+// Preconditions:
+//   - s may contain <details> tags with an optional <summary>
+//   - opts is non-nil
+//
+// Invariants:
+//   - Preserved tags are written with escape placeholders so they survive cleanup
+//   - The summary line is part of an HTML block, where Markdown is not
+//     rendered, so the summary is written as HTML text; its tags are
+//     removed and its entities are kept
+//   - Body content is left for the other converters
+//
+// Postconditions:
+//   - <details> and <summary> are emitted as bare HTML tags on their own lines
+//   - Summary text is wrapped in <b> unless opts.SummaryStyle is SummaryPlain
+//   - The body is separated by blank lines so Markdown inside it renders
+func convertDetails(s string, opts *Options) string {
+	return reDetails.ReplaceAllStringFunc(s, func(match string) string {
+		inner := reDetails.FindStringSubmatch(match)[1]
+		var sb strings.Builder
+		sb.WriteString("\n\n" + escapedTag("details") + "\n")
+		if m := reSummary.FindStringSubmatchIndex(inner); m != nil {
+			summary := reHtmlTag.ReplaceAllString(inner[m[2]:m[3]], "")
+			summary = strings.ReplaceAll(strings.TrimSpace(summary), "&", escAmp)
+			if opts.SummaryStyle == SummaryBold && summary != "" {
+				summary = escapedTag("b") + summary + escapedTag("/b")
+			}
+			sb.WriteString(escapedTag("summary") + summary + escapedTag("/summary") + "\n")
+			inner = inner[:m[0]] + inner[m[1]:]
+		}
+		sb.WriteString("\n" + strings.TrimSpace(inner) + "\n\n" + escapedTag("/details") + "\n\n")
+		return sb.String()
+	})
+}
+
+// escapedTag returns an HTML tag written with escape placeholders so that
+// it is preserved in the output by cleanupOutput.
+func escapedTag(name string) string {
+	return escLT + name + escGT
 }
 
 // convertHeadings converts HTML heading tags (h1-h6) to Markdown headings.
 //
 // Preconditions:
 //   - s may contain <h1> through <h6> tags
+//   - opts is non-nil
 //
 // Invariants:
 //   - Headings are processed from h6 to h1 to handle nested cases correctly
-//   - Tag attributes are ignored
+//   - Tag attributes other than id are ignored; id is only used when
+//     opts.HeadingAnchors is set
 //
 // Postconditions:
 //   - <h1> becomes "# text", <h2> becomes "## text", etc.
+//   - With opts.NormalizeHeadingBase, levels are first shifted so the
+//     highest heading in s becomes level 1
+//   - opts.HeadingOffset shifts levels, clamped to 1 through 6
+//   - With opts.HeadingStyle set to Setext, levels 1 and 2 are underlined instead
 //   - Each heading is surrounded by blank lines
 //   - Inner content is trimmed of whitespace
-func convertHeadings(s string) string {
+//   - With opts.StripHeadingNumbers, leading section numbers are removed
+//   - opts.HeadingTransform, if set, is applied to the trimmed content
+//   - With opts.HeadingAnchors, a heading's id is added as an anchor by
+//     anchorHeading
+func convertHeadings(s string, opts *Options) string {
+	base := 0
+	if opts.NormalizeHeadingBase {
+		base = 1 - minHeadingLevel(s)
+	}
 	for _, h := range headingDefs {
 		s = h.re.ReplaceAllStringFunc(s, func(match string) string {
-			inner := h.re.FindStringSubmatch(match)[1]
-			inner = strings.TrimSpace(inner)
-			return "\n\n" + h.prefix + inner + "\n\n"
+			m := h.re.FindStringSubmatch(match)
+			inner := strings.TrimSpace(m[2])
+			if opts.StripHeadingNumbers {
+				inner = stripHeadingNumber(inner)
+			}
+			if opts.HeadingTransform != nil {
+				inner = opts.HeadingTransform(inner)
+			}
+			heading := formatHeading(opts.headingLevel(h.level+base), inner, opts)
+			if id, ok := tagAttr(m[1], "id"); ok {
+				heading = anchorHeading(heading, strings.TrimSpace(id), opts.HeadingAnchors)
+			}
+			return "\n\n" + heading + "\n\n"
 		})
 	}
 	return s
 }
 
+// anchorHeading adds an anchor for id to a formatted heading.
+//
+// Postconditions:
+//   - AnchorAttr appends " {#id}" to the heading text line, which for a
+//     setext heading is the line above the underline
+//   - AnchorHTML puts an empty <a id="id"></a> on the line before the heading
+//   - AnchorNone, or an empty id, leaves the heading unchanged
+func anchorHeading(heading, id string, mode HeadingAnchors) string {
+	if id == "" {
+		return heading
+	}
+	switch mode {
+	case AnchorAttr:
+		text, underline, setext := strings.Cut(heading, "\n")
+		heading = text + " {#" + id + "}"
+		if setext {
+			heading += "\n" + underline
+		}
+	case AnchorHTML:
+		id = strings.ReplaceAll(escapeAngles(id), `"`, "&quot;")
+		heading = escLT + `a id="` + id + `"` + escGT + escLT + "/a" + escGT + "\n" + heading
+	}
+	return heading
+}
+
+// minHeadingLevel returns the smallest heading level in s, or 1 if s has
+// no headings.
+func minHeadingLevel(s string) int {
+	level := 6
+	matches := reHeadingLevel.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return 1
+	}
+	for _, m := range matches {
+		level = min(level, int(m[1][0]-'0'))
+	}
+	return level
+}
+
+// stripHeadingNumber removes a leading section number such as "1. ",
+// "2) ", or "3.1. " from heading text.
+//
+// Invariants:
+//   - Numbers have at most three digits per level, so years such as
+//     "2024. A Retrospective" are kept
+//   - The number must be followed by whitespace and more text, so a
+//     heading that is only a number is kept
+func stripHeadingNumber(text string) string {
+	if stripped := reHeadingNum.ReplaceAllString(text, ""); stripped != "" {
+		return stripped
+	}
+	return text
+}
+
+// convertHgroups reduces each <hgroup> to its primary heading followed by
+// subtitle lines.
+//
+// Preconditions:
+//   - s may contain <hgroup> tags wrapping headings and <p> subtitles
+//
+// Invariants:
+//   - Runs before convertHeadings, which converts the primary heading
+//
+// Postconditions:
+//   - The first heading in the hgroup is kept at its own level
+//   - Every later heading and every <p> becomes an italic paragraph,
+//     so "<h1>Title</h1><h2>Subtitle</h2>" renders as "# Title" and "*Subtitle*"
+//   - An hgroup without headings is left unchanged
+func convertHgroups(s string) string {
+	return reHgroup.ReplaceAllStringFunc(s, func(match string) string {
+		inner := reHgroup.FindStringSubmatch(match)[1]
+		if !reAnyHeading.MatchString(inner) {
+			return match
+		}
+		inner = reParagraph.ReplaceAllString(inner, "<p><em>$1</em></p>")
+		primary := true
+		return reAnyHeading.ReplaceAllStringFunc(inner, func(heading string) string {
+			if primary {
+				primary = false
+				return heading
+			}
+			text := strings.TrimSpace(reAnyHeading.FindStringSubmatch(heading)[1])
+			return "<p><em>" + text + "</em></p>"
+		})
+	})
+}
+
+// convertFormControls removes form controls whose content is not prose.
+//
+// Preconditions:
+//   - Runs after convertCheckboxes, so task list checkboxes are already text
+//
+// Invariants:
+//   - <label>, <button>, <output>, and <legend> are left for cleanup,
+//     which keeps their text inline
+//
+// Postconditions:
+//   - <select> and <datalist> are removed with their options, which would
+//     otherwise run together as text
+//   - Remaining <input> tags are removed with the spaces after them, so
+//     the text on either side is not separated by two spaces
+//   - A space separates a label, button, output, or legend from an
+//     adjacent one, or from a word directly before or after it
+func convertFormControls(s string) string {
+	s = reFormControl.ReplaceAllString(s, "")
+	s = reInput.ReplaceAllString(s, "")
+	s = reControlEnd.ReplaceAllString(s, "$1 $2")
+	return reControlStart.ReplaceAllString(s, "$1 $2")
+}
+
+// convertAMPMedia rewrites the media elements of AMP pages as standard
+// HTML, so they are converted like the elements they replace.
+//
+// Invariants:
+//   - Attributes are kept, so src, alt, and title are read as usual
+//
+// Postconditions:
+//   - <amp-img> and <amp-anim> become <img>; their content, typically a
+//     <noscript> fallback holding the same image, is dropped
+//   - <amp-video> and </amp-video> become <video> and </video>
+func convertAMPMedia(s string) string {
+	s = reAmpImg.ReplaceAllString(s, "<img$1>")
+	return reAmpVideoTag.ReplaceAllString(s, "<${1}video")
+}
+
+// convertPictures reduces each <picture> to a single <img>.
+//
+// Invariants:
+//   - The <img> fallback is preferred over every <source>, since it is
+//     the one choice valid for all viewers; media queries are ignored
+//
+// Postconditions:
+//   - A picture becomes its first <img>, with its attributes kept
+//   - A picture without <img> becomes an <img> of the first candidate
+//     URL of its first <source> with a srcset or src, or is dropped if
+//     there is none
+func convertPictures(s string) string {
+	return rePicture.ReplaceAllStringFunc(s, func(match string) string {
+		inner := rePicture.FindStringSubmatch(match)[1]
+		if img := reImg.FindString(inner); img != "" {
+			return img
+		}
+		for _, m := range reSource.FindAllStringSubmatch(inner, -1) {
+			set, ok := tagAttr(m[1], "srcset")
+			if !ok {
+				set, _ = tagAttr(m[1], "src")
+			}
+			if src := strings.Fields(strings.ReplaceAll(set, ",", " ")); len(src) > 0 {
+				return `<img src="` + src[0] + `">`
+			}
+		}
+		return ""
+	})
+}
+
+// convertFigures separates the caption of each <figure> from its content
+// and places it according to opts.FigureCaption.
+//
+// Preconditions:
+//   - Runs before convertParagraphs, which converts the caption paragraph
+//
+// Invariants:
+//   - The caption may come before or after the content in the source
+//
+// Postconditions:
+//   - The caption becomes an italic paragraph, like hgroup subtitles
+//   - The content and caption are separated by a blank line
+//   - A figure without a <figcaption> is reduced to its content
+func convertFigures(s string, opts *Options) string {
+	return reFigure.ReplaceAllStringFunc(s, func(match string) string {
+		inner := reFigure.FindStringSubmatch(match)[1]
+		caption := ""
+		if m := reFigcaption.FindStringSubmatch(inner); m != nil {
+			caption = strings.TrimSpace(m[1])
+		}
+		content := strings.TrimSpace(reFigcaption.ReplaceAllString(inner, ""))
+		if caption == "" {
+			return "\n\n" + content + "\n\n"
+		}
+		caption = "<p><em>" + caption + "</em></p>"
+		if opts.FigureCaption == CaptionAbove {
+			return "\n\n" + caption + "\n\n" + content + "\n\n"
+		}
+		return "\n\n" + content + "\n\n" + caption + "\n\n"
+	})
+}
+
+// formatHeading renders heading text at the given level.
+//
+// Preconditions:
+//   - level is between 1 and 6
+//
+// Invariants:
+//   - Setext style only exists for levels 1 and 2; deeper levels use ATX
+//
+// Postconditions:
+//   - ATX headings are "#" repeated level times, a space, and the text
+//   - Setext headings are the text underlined with = (level 1) or - (level 2)
+//     at least three characters long
+func formatHeading(level int, text string, opts *Options) string {
+	if opts.HeadingStyle == Setext && level <= 2 {
+		underline := "="
+		if level == 2 {
+			underline = "-"
+		}
+		width := max(utf8.RuneCountInString(reHtmlTag.ReplaceAllString(text, "")), 3)
+		return text + "\n" + strings.Repeat(underline, width)
+	}
+	return strings.Repeat("#", level) + " " + text
+}
+
 // convertParagraphs converts HTML <p> tags to plain text with surrounding blank lines.
 //
 // Preconditions:
@@ -227,10 +953,17 @@ func convertParagraphs(s string) string {
 //
 // Invariants:
 //   - Multi-line content is preserved with each line prefixed by "> "
-//   - Empty lines within blockquote are removed
+//   - Paragraphs and horizontal rules have already been converted, so
+//     blank lines separate paragraphs
 //
 // Postconditions:
 //   - Each non-empty line is prefixed with "> "
+//   - Each run of blank lines between content becomes one empty ">" line,
+//     keeping quoted paragraphs separate; a rule needs no separator after it
+//   - A "---" rule following text is preceded by an empty ">" line,
+//     so it is not read as a Setext heading underline
+//   - A leading GFM alert marker such as "> [!NOTE]" is directly followed
+//     by the content, with no empty ">" line
 //   - Blockquote is surrounded by blank lines
 func convertBlockquotes(s string) string {
 	return reBlockquote.ReplaceAllStringFunc(s, func(match string) string {
@@ -238,303 +971,1605 @@ func convertBlockquotes(s string) string {
 		inner = strings.TrimSpace(inner)
 		lines := strings.Split(inner, "\n")
 		var quoted []string
+		blank := false
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
-			if line != "" {
-				quoted = append(quoted, "> "+line)
+			if line == "" {
+				blank = true
+				continue
 			}
+			prev := ""
+			if len(quoted) > 0 {
+				prev = quoted[len(quoted)-1]
+			}
+			alert := len(quoted) == 1 && reAlertMarker.MatchString(prev)
+			if (blank || line == "---") && prev != "" && prev != ">" && prev != "> ---" && !alert {
+				quoted = append(quoted, ">")
+			}
+			blank = false
+			quoted = append(quoted, "> "+line)
 		}
 		return "\n\n" + strings.Join(quoted, "\n") + "\n\n"
 	})
 }
 
-// convertCodeBlocks converts HTML <pre> and <pre><code> tags to Markdown fenced code blocks.
+// convertCodeBlocks converts HTML <pre> and <pre><code> tags to Markdown code blocks.
 //
 // Preconditions:
 //   - s may contain <pre><code>...</code></pre> or <pre>...</pre> tags
+//   - opts is non-nil
 //
 // Invariants:
 //   - <pre><code> is processed before <pre> to avoid double conversion
+//   - Markup inside code, such as syntax highlighting spans, is removed
 //   - HTML entities inside code are decoded
+//   - Internal blank lines are preserved
 //
 // Postconditions:
-//   - Code is wrapped in ``` fences
+//   - Decoded angle brackets are escaped to survive cleanup
+//   - Code is formatted by codeBlock according to opts.CodeBlockStyle
 //   - Code block is surrounded by blank lines
-func convertCodeBlocks(s string) string {
+func convertCodeBlocks(s string, opts *Options) string {
 	s = rePreCode.ReplaceAllStringFunc(s, func(match string) string {
 		inner := rePreCode.FindStringSubmatch(match)[1]
-		inner = decodeHTMLEntities(inner)
-		return "\n\n```\n" + inner + "\n```\n\n"
+		return "\n\n" + codeBlock(inner, opts) + "\n\n"
 	})
 
 	// Handle pre without code
 	s = rePre.ReplaceAllStringFunc(s, func(match string) string {
 		inner := rePre.FindStringSubmatch(match)[1]
-		inner = decodeHTMLEntities(inner)
-		return "\n\n```\n" + inner + "\n```\n\n"
+		return "\n\n" + codeBlock(inner, opts) + "\n\n"
 	})
 
 	return s
 }
 
-// convertHorizontalRules converts HTML <hr> tags to Markdown horizontal rules.
+// codeBlock renders the inner HTML of a <pre> as a Markdown code block.
+//
+// Preconditions:
+//   - inner is the raw HTML between the <pre> or <code> tags
+//
+// Postconditions:
+//   - One leading and one trailing newline inside the code are removed
+//     so the block hugs the code
+//   - If opts.TabWidth > 0, leading tabs are expanded to spaces
+//   - If opts.DedentCode is set, common indentation is removed
+//   - Fenced blocks are wrapped in ``` fences
+//   - Indented blocks prefix each non-blank line with four spaces, written
+//     as escCodeIndent
+func codeBlock(inner string, opts *Options) string {
+	code := reHtmlTag.ReplaceAllString(inner, "")
+	code = decodeHTMLEntities(code)
+	code = escapeAngles(code)
+	code = expandLeadingTabs(trimCodeNewlines(code), opts.TabWidth)
+	if opts.DedentCode {
+		code = dedentCode(code)
+	}
+
+	if opts.CodeBlockStyle == Indented {
+		lines := strings.Split(code, "\n")
+		for i, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				lines[i] = escCodeIndent + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return "```\n" + code + "\n```"
+}
+
+// convertLineNumberTables rewrites code rendered by syntax highlighters as
+// a table with a line-number column into a plain <pre><code> block.
+//
+// Preconditions:
+//   - Runs before normalizeWhitespace, so the indentation of code held
+//     in cells without <pre> is still intact
+//
+// Invariants:
+//   - A table qualifies if its class is highlight or highlighttable, or
+//     if it has a line-number cell (class lineno, linenos, gutter,
+//     line-number, or blob-num); other tables are unchanged
+//
+// Postconditions:
+//   - Line-number cells are discarded
+//   - The code column is a cell with a "code" class, or the last cell of
+//     each row; the <pre> inside it, if any, is unwrapped
+//   - Code from consecutive rows is joined with newlines, so both one row
+//     for the whole block and one row per line are handled
+func convertLineNumberTables(s string) string {
+	return reTable.ReplaceAllStringFunc(s, func(match string) string {
+		open := reTableTag.FindString(match)
+		cells := reCell.FindAllStringSubmatch(match, -1)
+		isCode := reHighlightTbl.MatchString(open) || slices.ContainsFunc(cells, func(c []string) bool {
+			class, _ := tagAttr(c[2], "class")
+			return reLineNoCell.MatchString(class)
+		})
+		if !isCode {
+			return match
+		}
+
+		var lines []string
+		for _, row := range reRow.FindAllStringSubmatch(match, -1) {
+			var code []string
+			for _, c := range reCell.FindAllStringSubmatch(row[1], -1) {
+				class, _ := tagAttr(c[2], "class")
+				if reLineNoCell.MatchString(class) {
+					continue
+				}
+				if slices.Contains(strings.Fields(strings.ToLower(class)), "code") {
+					code = []string{c[3]}
+					break
+				}
+				code = []string{c[3]}
+			}
+			for _, content := range code {
+				if m := rePre.FindStringSubmatch(content); m != nil {
+					content = trimCodeNewlines(m[1])
+				}
+				lines = append(lines, content)
+			}
+		}
+		return "<pre><code>" + strings.Join(lines, "\n") + "</code></pre>"
+	})
+}
+
+// convertPreStyled rewrites <div>, <span>, and <p> elements whose style
+// preserves whitespace as <pre> blocks, so they are converted like <pre>.
+//
+// Preconditions:
+//   - Runs before normalizeWhitespace, so the whitespace is still intact
+//
+// Invariants:
+//   - An element qualifies if its style sets white-space to pre or
+//     pre-wrap; other elements are unchanged
+//   - Nested elements of the same name are balanced to find the end
+//
+// Postconditions:
+//   - The element becomes <pre> holding its inner HTML, with <br> tags
+//     written as newlines since codeBlock removes tags
+func convertPreStyled(s string) string {
+	var sb strings.Builder
+	last := 0
+	for {
+		loc := rePreStyled.FindStringSubmatchIndex(s[last:])
+		if loc == nil {
+			break
+		}
+		start, openEnd := last+loc[0], last+loc[1]
+		style, _ := tagAttr(s[last+loc[4]:last+loc[5]], "style")
+		if ws := parseStyle(style)["white-space"]; ws != "pre" && ws != "pre-wrap" {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		var reTag *regexp.Regexp
+		switch strings.ToLower(s[last+loc[2] : last+loc[3]]) {
+		case "div":
+			reTag = reDivTag
+		case "span":
+			reTag = reSpanTag
+		default:
+			reTag = reParaTag
+		}
+		closeStart, closeEnd := matchingCloseTag(s[openEnd:], reTag)
+		if closeStart < 0 {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		inner := reBr.ReplaceAllString(s[openEnd:openEnd+closeStart], "\n")
+		sb.WriteString(s[last:start] + "<pre>" + inner + "</pre>")
+		last = openEnd + closeEnd
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// expandLeadingTabs replaces each tab in the indentation of every line of
+// code with width spaces. Tabs after the first non-whitespace character
+// are kept. A width of 0 or less returns code unchanged.
+func expandLeadingTabs(code string, width int) string {
+	if width <= 0 {
+		return code
+	}
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		rest := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(rest)]
+		lines[i] = strings.ReplaceAll(indent, "\t", strings.Repeat(" ", width)) + rest
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dedentCode removes the leading whitespace common to all non-blank lines
+// of code, keeping relative indentation.
+//
+// Invariants:
+//   - Indentation is compared character by character, so a tab and
+//     spaces are never treated as equal; set TabWidth to expand tabs first
+//
+// Postconditions:
+//   - At least one non-blank line starts without the removed prefix
+//   - Blank lines lose up to the same prefix
+func dedentCode(code string) string {
+	lines := strings.Split(code, "\n")
+	prefix, found := "", false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(indent) && prefix[n] == indent[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	if prefix == "" {
+		return code
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+		if strings.TrimSpace(lines[i]) == "" && !strings.HasPrefix(line, prefix) {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimCodeNewlines removes exactly one leading and one trailing newline
+// from code, which HTML authors commonly place after <pre> and before </pre>.
+func trimCodeNewlines(code string) string {
+	code = strings.TrimPrefix(code, "\n")
+	return strings.TrimSuffix(code, "\n")
+}
+
+// convertHorizontalRules converts HTML <hr> tags to Markdown horizontal rules.
+//
+// Preconditions:
+//   - s may contain <hr> or <hr/> tags
+//
+// Invariants:
+//   - Both self-closing and non-self-closing forms are handled
+//
+// Postconditions:
+//   - <hr> becomes "---" surrounded by blank lines
+func convertHorizontalRules(s string) string {
+	return reHr.ReplaceAllString(s, "\n\n---\n\n")
+}
+
+// convertSeparators replaces the opening and closing tags of each <div>
+// whose class is one of opts.SeparatorClasses with an <hr>.
+//
+// Invariants:
+//   - Nested divs are balanced to find the closing tag
+//   - The content of a separator is kept, so an image in it is not lost
+//
+// Postconditions:
+//   - A separator becomes <hr> followed by its content
+func convertSeparators(s string, opts *Options) string {
+	var sb strings.Builder
+	last := 0
+	for {
+		loc := reDivOpen.FindStringSubmatchIndex(s[last:])
+		if loc == nil {
+			break
+		}
+		openEnd := last + loc[1]
+		class, _ := tagAttr(s[last+loc[2]:last+loc[3]], "class")
+		if !opts.isSeparator(class) {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+		closeStart, closeEnd := matchingCloseTag(s[openEnd:], reDivTag)
+		if closeStart < 0 {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+		sb.WriteString(s[last:last+loc[0]] + "<hr>" + s[openEnd:openEnd+closeStart])
+		last = openEnd + closeEnd
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// convertAdmonitions rewrites admonition elements as blockquotes that
+// start with a GFM alert marker.
+//
+// Preconditions:
+//   - Runs before block conversion, so the content is converted as usual
+//     and quoted by convertBlockquotes
+//
+// Invariants:
+//   - An element qualifies if opts.admonitionType finds its class
+//   - Nested elements of the same name are balanced to find the end
+//
+// Postconditions:
+//   - <div class="warning">text</div> becomes
+//     <blockquote>[!WARNING] text</blockquote>, written with the marker
+//     on its own line
+//   - A leading admonition-title element is removed
+func convertAdmonitions(s string, opts *Options) string {
+	var sb strings.Builder
+	last := 0
+	for {
+		loc := reAdmonition.FindStringSubmatchIndex(s[last:])
+		if loc == nil {
+			break
+		}
+		openEnd := last + loc[1]
+		class, _ := tagAttr(s[last+loc[4]:last+loc[5]], "class")
+		alert := opts.admonitionType(class)
+		if alert == "" {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		var reTag *regexp.Regexp
+		switch strings.ToLower(s[last+loc[2] : last+loc[3]]) {
+		case "div":
+			reTag = reDivTag
+		case "aside":
+			reTag = reAsideTag
+		default:
+			reTag = reSectionTag
+		}
+		closeStart, closeEnd := matchingCloseTag(s[openEnd:], reTag)
+		if closeStart < 0 {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		inner := s[openEnd : openEnd+closeStart]
+		if m := reAdmonTitle.FindStringSubmatch(inner); m != nil {
+			if title, _ := tagAttr(m[2], "class"); slices.Contains(strings.Fields(strings.ToLower(title)), "admonition-title") {
+				inner = inner[len(m[0]):]
+			}
+		}
+		sb.WriteString(s[last:last+loc[0]] + "<blockquote>[!" + alert + "]\n" + inner + "</blockquote>")
+		last = openEnd + closeEnd
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// convertCheckboxes converts <input type="checkbox"> elements to GFM task
+// markers.
+//
+// Invariants:
+//   - Other input types are left for cleanup
+//
+// Postconditions:
+//   - A checked box becomes "[x] " and an unchecked one "[ ] ", replacing
+//     whitespace after the tag, so the label text follows on the same line
+//   - At the start of a list item this yields a "- [x] item" task list item;
+//     elsewhere, such as a bare checkbox with a <label>, the marker is inline
+func convertCheckboxes(s string) string {
+	return reInput.ReplaceAllStringFunc(s, func(match string) string {
+		attrs := reInput.FindStringSubmatch(match)[1]
+		if typ, _ := tagAttr(attrs, "type"); !strings.EqualFold(strings.TrimSpace(typ), "checkbox") {
+			return match
+		}
+		if hasAttr(attrs, "checked") {
+			return "[x] "
+		}
+		return "[ ] "
+	})
+}
+
+// convertLists converts both unordered and ordered HTML lists to Markdown.
+//
+// Preconditions:
+//   - s may contain <ul> and/or <ol> tags with nested <li> items
+//   - opts is non-nil
+//
+// Invariants:
+//   - Lists are processed in document order, outermost first
+//   - Nested lists are converted before their parent's items are formatted
+//
+// Postconditions:
+//   - <ul> lists become "- item" format
+//   - <ol> lists become "N. item" format with sequential numbering
+//   - Nested lists are indented under their parent item
+func convertLists(s string, opts *Options) string {
+	c := &listConverter{opts: opts}
+	return c.convert(s, 0)
+}
+
+// listConverter holds the state of a single convertLists call.
+//
+// Invariants:
+//   - next[d] is the number following the last item of the previous
+//     ordered list at nesting depth d, or 0 if there was none
+type listConverter struct {
+	opts *Options
+	next []int
+}
+
+// convert converts every list in s whose nesting depth is depth.
+//
+// Preconditions:
+//   - depth is 0 for the document and increases by one per enclosing list
+//
+// Invariants:
+//   - Unbalanced list tags are left for cleanup
+//
+// Postconditions:
+//   - Top-level lists are surrounded by blank lines
+//   - Nested lists are surrounded by single newlines so they stay
+//     attached to their parent item; whitespace around them in the
+//     source is dropped
+func (c *listConverter) convert(s string, depth int) string {
+	locs := reListTag.FindAllStringSubmatchIndex(s, -1)
+	var sb strings.Builder
+	pos := 0
+	for i := 0; i < len(locs); i++ {
+		open := locs[i]
+		if open[3] > open[2] {
+			// Stray closing tag
+			continue
+		}
+		end := matchingListClose(locs, i)
+		if end < 0 {
+			continue
+		}
+		tag := strings.ToLower(s[open[4]:open[5]])
+		attrs := s[open[6]:open[7]]
+		inner := c.convert(s[open[1]:locs[end][0]], depth+1)
+
+		before := s[pos:open[0]]
+		pos = locs[end][1]
+		if depth == 0 {
+			sb.WriteString(before)
+			sb.WriteString("\n\n" + c.convertItems(inner, tag == "ol", attrs, depth) + "\n\n")
+		} else {
+			// Source indentation around a nested list is insignificant and
+			// would otherwise leave a blank line inside the parent item
+			sb.WriteString(strings.TrimRight(before, " \t\n"))
+			sb.WriteString("\n" + c.convertItems(inner, tag == "ol", attrs, depth) + "\n")
+			for pos < len(s) && strings.ContainsRune(" \t\n", rune(s[pos])) {
+				pos++
+			}
+		}
+		i = end
+	}
+	sb.WriteString(s[pos:])
+	return sb.String()
+}
+
+// matchingListClose returns the index in locs of the closing list tag that
+// balances the opening tag at locs[i], or -1 if there is none.
+func matchingListClose(locs [][]int, i int) int {
+	level := 0
+	for j := i; j < len(locs); j++ {
+		if locs[j][3] > locs[j][2] {
+			level--
+			if level == 0 {
+				return j
+			}
+		} else {
+			level++
+		}
+	}
+	return -1
+}
+
+// convertItems formats the items of one list, choosing its first number.
+//
+// Preconditions:
+//   - s is the inner content of the list with nested lists already converted
+//   - attrs is the attribute text of the <ul> or <ol> tag
+//
+// Invariants:
+//   - An explicit start attribute always wins
+//   - Otherwise ordered lists start at 1, or continue from the previous
+//     ordered list at the same depth when opts.ContinueOrderedNumbering is set
+//   - A reversed ordered list counts down, starting at its item count
+//     unless start is given, and is never continued from or by another list
+//
+// Postconditions:
+//   - Returns the formatted items and records the next number for depth
+func (c *listConverter) convertItems(s string, ordered bool, attrs string, depth int) string {
+	for len(c.next) <= depth {
+		c.next = append(c.next, 0)
+	}
+	start, step := 1, 1
+	if ordered {
+		if hasAttr(attrs, "reversed") {
+			start, step = len(reLi.FindAllStringIndex(s, -1)), -1
+		} else if c.opts.ContinueOrderedNumbering && c.next[depth] > 0 {
+			start = c.next[depth]
+		}
+		if v, ok := tagAttr(attrs, "start"); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				start = n
+			}
+		}
+	}
+	items, next := convertListItems(s, ordered, start, step, c.opts)
+	if ordered {
+		c.next[depth] = 0
+		if step > 0 {
+			c.next[depth] = next
+		}
+	}
+	return items
+}
+
+// convertListItems extracts and formats list items from HTML <li> tags.
+//
+// Preconditions:
+//   - s contains the inner content of a <ul> or <ol> tag
+//   - ordered indicates whether to use numbered or bulleted format
+//   - start is the number of the first item of an ordered list
+//   - step is 1, or -1 for a reversed list
+//   - opts is non-nil
+//
+// Invariants:
+//   - Nested <p> tags within list items become paragraph breaks
+//   - Items are numbered sequentially from start by step for ordered lists;
+//     an item's value attribute sets its number and numbering continues from it
+//   - Text outside <li> tags is never dropped
+//
+// Postconditions:
+//   - Returns newline-separated list items (blank-line separated if opts.LooseLists)
+//     and the number the next item would have had
+//   - Each item is prefixed with "- " (or opts.BulletMarker) or "N. " (ordered)
+//   - Continuation lines are indented to the marker width
+//   - Loose text before the first item is emitted as a plain line before the list
+//   - Loose text after an item is attached to that item as a continuation line
+func convertListItems(s string, ordered bool, start, step int, opts *Options) (string, int) {
+	lead := ""
+	var contents, attrs []string
+	attachLoose := func(text string) {
+		text = strings.TrimSpace(text)
+		switch {
+		case text == "":
+		case len(contents) == 0:
+			lead = text
+		default:
+			contents[len(contents)-1] += "\n" + text
+		}
+	}
+	pos := 0
+	for _, loc := range reLi.FindAllStringSubmatchIndex(s, -1) {
+		attachLoose(s[pos:loc[0]])
+		attrs = append(attrs, s[loc[2]:loc[3]])
+		contents = append(contents, s[loc[4]:loc[5]])
+		pos = loc[1]
+	}
+	attachLoose(s[pos:])
+
+	var items []string
+	n := start
+	for i, content := range contents {
+		// Nested p tags separate paragraphs within the item
+		content = rePTag.ReplaceAllString(content, "\n\n")
+		marker := opts.bulletMarker() + " "
+		if ordered {
+			if v, ok := tagAttr(attrs[i], "value"); ok {
+				if value, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+					n = value
+				}
+			}
+			marker = strconv.Itoa(n) + ". "
+			n += step
+		}
+		items = append(items, marker+indentListItemContent(content, len(marker)))
+	}
+
+	sep := "\n"
+	if opts.LooseLists {
+		sep = "\n\n"
+	}
+	result := strings.Join(items, sep)
+	if lead != "" {
+		result = lead + "\n\n" + result
+	}
+	return result, n
+}
+
+// indentListItemContent formats the content of a list item as Markdown
+// continuation lines.
+//
+// Preconditions:
+//   - s is the inner content of a single <li> tag
+//   - indent is the width of the item's list marker
+//
+// Invariants:
+//   - Blank lines separate paragraphs; runs of blank lines collapse to one
+//   - Lines inside fenced code blocks keep their indentation and blank
+//     lines, so that the code is unchanged
+//   - Indentation is written as escIndent so that it survives the line
+//     trimming of enclosing list items
+//
+// Postconditions:
+//   - Returns trimmed content whose first line has no indentation
+//   - Every following non-blank line is indented by indent spaces
+//   - Paragraphs are separated by a single blank line
+func indentListItemContent(s string, indent int) string {
+	pad := strings.Repeat(escIndent, indent)
+	var sb strings.Builder
+	blank := false
+	fence := ""
+	for line := range strings.SplitSeq(s, "\n") {
+		if fence != "" {
+			sb.WriteString("\n")
+			if strings.TrimSpace(line) != "" {
+				sb.WriteString(pad + line)
+			}
+			if t := trimIndentEscapes(line); strings.HasPrefix(t, fence) && strings.Trim(t, "`") == "" {
+				fence = ""
+			}
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = sb.Len() > 0
+			continue
+		}
+		if sb.Len() > 0 {
+			if blank {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n" + pad)
+		}
+		sb.WriteString(line)
+		blank = false
+		if t := trimIndentEscapes(line); strings.HasPrefix(t, "```") {
+			fence = t[:len(t)-len(strings.TrimLeft(t, "`"))]
+		}
+	}
+	return sb.String()
+}
+
+// trimIndentEscapes removes leading whitespace and the escIndent
+// placeholders written for nested list items from line.
+func trimIndentEscapes(line string) string {
+	for {
+		line = strings.TrimLeft(line, " \t")
+		rest, ok := strings.CutPrefix(line, escIndent)
+		if !ok {
+			return line
+		}
+		line = rest
+	}
+}
+
+// convertTables converts HTML <table> tags to Markdown tables.
+//
+// Preconditions:
+//   - s may contain <table> tags with <tr>, <th>, and <td> elements
+//   - opts is non-nil
+//   - report may be nil
+//
+// Invariants:
+//   - Delegates row processing to convertTableContent
+//   - Nested tables are flattened by flattenNestedTables first
+//   - Tables are numbered from 1 in warnings, in document order
+//
+// Postconditions:
+//   - Table is converted to pipe-delimited Markdown format
+//   - Table is surrounded by blank lines
+func convertTables(s string, opts *Options, report *ConversionReport) string {
+	s = flattenNestedTables(s)
+	table := 0
+	return reTable.ReplaceAllStringFunc(s, func(match string) string {
+		table++
+		inner := reTable.FindStringSubmatch(match)[1]
+		return "\n\n" + convertTableContent(inner, table, opts, report) + "\n\n"
+	})
+}
+
+// flattenNestedTables replaces each table nested inside another table with
+// a compact inline representation, since Markdown tables cannot nest.
+//
+// Without this, the non-greedy reTable would end the outer table at the
+// nested </table> and leak the remaining rows as raw tags.
+//
+// Invariants:
+//   - Innermost tables are flattened first, so any depth is handled
+//   - Top-level tables are unchanged
+//
+// Postconditions:
+//   - No <table> remains inside another <table>
+//   - A nested table becomes its rows separated by "; ", with the cells
+//     of each row separated by ", "
+func flattenNestedTables(s string) string {
+	for {
+		start, end, found := -1, -1, false
+		depth := 0
+		for _, loc := range reTableTag.FindAllStringSubmatchIndex(s, -1) {
+			if s[loc[2]:loc[3]] == "" {
+				if depth > 0 {
+					start = loc[0]
+				}
+				depth++
+				continue
+			}
+			depth--
+			if start >= 0 {
+				end, found = loc[1], true
+				break
+			}
+		}
+		if !found {
+			return s
+		}
+		s = s[:start] + inlineTable(s[start:end]) + s[end:]
+	}
+}
+
+// inlineTable renders a single table without nested tables as inline text.
+func inlineTable(table string) string {
+	var rows []string
+	for _, row := range reRow.FindAllStringSubmatch(table, -1) {
+		cells := extractCells(row[1])
+		if len(cells) == 0 {
+			continue
+		}
+		contents := make([]string, len(cells))
+		for i, cell := range cells {
+			contents[i] = strings.Join(strings.Fields(cell.content), " ")
+		}
+		rows = append(rows, strings.Join(contents, ", "))
+	}
+	return strings.Join(rows, "; ")
+}
+
+// convertTableContent processes the inner content of an HTML table.
+//
+// Preconditions:
+//   - s contains <tr> rows with <th> and/or <td> cells
+//   - table is the 1-based index of the table, used in warnings
+//   - opts is non-nil
+//   - report may be nil
+//
+// Invariants:
+//   - First row is treated as header
+//   - Separator row is inserted after header
+//   - Column alignment comes from the first cell in the column with an
+//     explicit alignment, falling back to the <col> alignment
+//
+// Postconditions:
+//   - Returns pipe-delimited table with header separator
+//   - The separator marks left, right, and center aligned columns
+//   - Empty rows are skipped
+//   - Returns empty string if no valid rows found
+//   - Rows whose cell count differs from the header are reported as warnings
+//   - Cell content is escaped by escapeCell
+//   - With opts.SingleColumnTableAsList, a table whose rows all have one
+//     cell is rendered by singleColumnList instead
+func convertTableContent(s string, table int, opts *Options, report *ConversionReport) string {
+	// Extract rows
+	var rows [][]tableCell
+	for i, row := range reRow.FindAllStringSubmatch(s, -1) {
+		cells := extractCells(row[1])
+		if len(cells) == 0 {
+			continue
+		}
+		if len(rows) > 0 && len(cells) != len(rows[0]) {
+			report.addWarning("table %d row %d had %d cells, header had %d", table, i+1, len(cells), len(rows[0]))
+		}
+		rows = append(rows, cells)
+	}
+
+	if len(rows) == 0 {
+		return ""
+	}
+	if opts.SingleColumnTableAsList && !slices.ContainsFunc(rows, func(cells []tableCell) bool {
+		return len(cells) != 1
+	}) {
+		return singleColumnList(rows, opts)
+	}
+
+	aligns := columnAligns(s, rows)
+	var result []string
+	for i, cells := range rows {
+		contents := make([]string, len(cells))
+		for j, cell := range cells {
+			contents[j] = escapeCell(cellBreaks(cell.content, opts.TableCellBreak))
+		}
+		result = append(result, "| "+strings.Join(contents, " | ")+" |")
+
+		// Add separator after first row (header)
+		if i == 0 {
+			var sep strings.Builder
+			sep.WriteString("|")
+			for j := range cells {
+				sep.WriteString(" " + alignSeparator(aligns[j]) + " |")
+			}
+			result = append(result, sep.String())
+		}
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// cellBreaks renders the <br> tags of a table cell according to style,
+// before convertLineBreaks could turn them into row-ending hard breaks.
+func cellBreaks(content string, style TableCellBreak) string {
+	switch style {
+	case SpaceBreak:
+		return reSpacedBr.ReplaceAllString(content, " ")
+	case StripBreak:
+		return reBr.ReplaceAllString(content, "")
+	}
+	return reBr.ReplaceAllString(content, escapedTag("br"))
+}
+
+// escapeCell makes cell content safe inside a pipe table row.
+//
+// Invariants:
+//   - Tags are left unchanged, so pipes in attribute values such as
+//     href are not escaped
+//
+// Postconditions:
+//   - Literal | in text is escaped as \|, which GFM also honors in code spans
+//   - Newlines are replaced by spaces, since a row must be a single line
+func escapeCell(content string) string {
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range reHtmlTag.FindAllStringIndex(content, -1) {
+		sb.WriteString(escapeCellText(content[pos:loc[0]]))
+		sb.WriteString(strings.ReplaceAll(content[loc[0]:loc[1]], "\n", " "))
+		pos = loc[1]
+	}
+	sb.WriteString(escapeCellText(content[pos:]))
+	return sb.String()
+}
+
+// escapeCellText escapes the text between tags of a table cell.
+func escapeCellText(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// singleColumnList renders a one-column table as an unordered list.
+//
+// Preconditions:
+//   - rows is non-empty and every row has exactly one cell
+//
+// Postconditions:
+//   - Each cell becomes a list item using opts.bulletMarker
+//   - A <th> first row becomes a heading when opts.SingleColumnHeaderLevel
+//     is between 1 and 6, and the first item otherwise
+func singleColumnList(rows [][]tableCell, opts *Options) string {
+	var sb strings.Builder
+	if level := opts.SingleColumnHeaderLevel; level >= 1 && level <= 6 && rows[0][0].header {
+		sb.WriteString(formatHeading(level, rows[0][0].content, opts) + "\n\n")
+		rows = rows[1:]
+	}
+	for i, cells := range rows {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(opts.bulletMarker() + " " + cells[0].content)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// tableCell is a single <th> or <td> cell.
+type tableCell struct {
+	content string // trimmed inner HTML
+	align   string // "left", "right", "center", or "" if unspecified
+	header  bool   // true for <th>
+}
+
+// extractCells extracts cells from an HTML table row.
+//
+// Preconditions:
+//   - row contains <th> and/or <td> elements
+//
+// Invariants:
+//   - Cells are extracted in document order
+//   - Cell content is trimmed of whitespace
+//
+// Postconditions:
+//   - Returns slice of cells in order
+//   - Returns empty slice if no cells found
+func extractCells(row string) []tableCell {
+	var cells []tableCell
+	for _, m := range reCell.FindAllStringSubmatch(row, -1) {
+		cells = append(cells, tableCell{
+			content: strings.TrimSpace(m[3]),
+			align:   cellAlign(m[2]),
+			header:  strings.EqualFold(m[1], "th"),
+		})
+	}
+	return cells
+}
+
+// columnAligns determines the alignment of each header column.
+//
+// Preconditions:
+//   - s is the inner content of the table, possibly containing <col> tags
+//   - rows is non-empty
+//
+// Invariants:
+//   - Explicit cell alignment overrides <col> alignment
+//   - <col span="N"> applies to N columns
+//
+// Postconditions:
+//   - Returns one alignment per cell of the header row
+func columnAligns(s string, rows [][]tableCell) []string {
+	aligns := make([]string, len(rows[0]))
+	i := 0
+	for _, m := range reCol.FindAllStringSubmatch(s, -1) {
+		span := 1
+		if v, ok := tagAttr(m[1], "span"); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && n > 0 {
+				span = n
+			}
+		}
+		align := cellAlign(m[1])
+		for ; span > 0 && i < len(aligns); span-- {
+			aligns[i] = align
+			i++
+		}
+	}
+
+	for col := range aligns {
+		for _, cells := range rows {
+			if col < len(cells) && cells[col].align != "" {
+				aligns[col] = cells[col].align
+				break
+			}
+		}
+	}
+	return aligns
+}
+
+// cellAlign returns the horizontal alignment declared in the attribute
+// text of a <th>, <td>, or <col> tag.
+//
+// Invariants:
+//   - The style text-align property overrides the legacy align attribute
+//   - "start" and "end" are treated as left and right
+//
+// Postconditions:
+//   - Returns "left", "right", "center", or "" if unspecified
+func cellAlign(attrs string) string {
+	align, _ := tagAttr(attrs, "align")
+	if style, ok := tagAttr(attrs, "style"); ok {
+		if v, ok := parseStyle(style)["text-align"]; ok {
+			align = v
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(align)) {
+	case "left", "start":
+		return "left"
+	case "right", "end":
+		return "right"
+	case "center":
+		return "center"
+	}
+	return ""
+}
+
+// alignSeparator returns the separator row marker for an alignment.
+func alignSeparator(align string) string {
+	switch align {
+	case "left":
+		return ":---"
+	case "right":
+		return "---:"
+	case "center":
+		return ":---:"
+	}
+	return "---"
+}
+
+// convertRuby converts <ruby> annotations according to opts.RubyStyle.
+//
+// Preconditions:
+//   - s may contain <ruby> tags with <rt>, <rp>, and <rb> children
+//   - opts is non-nil
+//
+// Invariants:
+//   - <rp> fallback parentheses are removed, since the reading is
+//     parenthesized by RubyParens itself
+//   - Each base text is paired with the <rt> that follows it, so
+//     <ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby> keeps per-character readings
+//
+// Postconditions:
+//   - RubyParens renders base(reading) for each pair
+//   - RubyBaseOnly renders only the base text
+//   - RubyHTML renders <ruby>base<rt>reading</rt></ruby>, preserved in the output
+func convertRuby(s string, opts *Options) string {
+	return reRuby.ReplaceAllStringFunc(s, func(match string) string {
+		inner := reRuby.FindStringSubmatch(match)[1]
+		inner = reRubyRp.ReplaceAllString(inner, "")
+		inner = reRubyBaseTag.ReplaceAllString(inner, "")
+
+		var sb strings.Builder
+		pos := 0
+		for _, loc := range reRubyRt.FindAllStringSubmatchIndex(inner, -1) {
+			base := strings.TrimSpace(inner[pos:loc[0]])
+			reading := strings.TrimSpace(inner[loc[2]:loc[3]])
+			switch opts.RubyStyle {
+			case RubyBaseOnly:
+				sb.WriteString(base)
+			case RubyHTML:
+				sb.WriteString(base + escapedTag("rt") + reading + escapedTag("/rt"))
+			default:
+				sb.WriteString(base + "(" + reading + ")")
+			}
+			pos = loc[1]
+		}
+		sb.WriteString(strings.TrimSpace(inner[pos:]))
+
+		if opts.RubyStyle == RubyHTML {
+			return escapedTag("ruby") + sb.String() + escapedTag("/ruby")
+		}
+		return sb.String()
+	})
+}
+
+// convertAbbreviations appends the title of each <abbr> in parentheses
+// the first time it is used.
+//
+// Invariants:
+//   - Occurrences are tracked per call, so per conversion
+//   - An abbreviation is identified by its text and title together, so
+//     the same text with a different title is expanded again
+//
+// Postconditions:
+//   - The first <abbr title="HyperText Markup Language">HTML</abbr>
+//     becomes "HTML (HyperText Markup Language)"; later ones become "HTML"
+//   - An <abbr> without a title, or with an empty one, becomes its text
+func convertAbbreviations(s string) string {
+	seen := make(map[[2]string]bool)
+	return reAbbr.ReplaceAllStringFunc(s, func(match string) string {
+		m := reAbbr.FindStringSubmatch(match)
+		text := m[2]
+		title, _ := tagAttr(m[1], "title")
+		title = strings.Join(strings.Fields(title), " ")
+		key := [2]string{strings.TrimSpace(text), title}
+		if title == "" || seen[key] {
+			return text
+		}
+		seen[key] = true
+		return text + " (" + title + ")"
+	})
+}
+
+// convertQuotes wraps inline <q> quotations in double quotes and appends
+// a link to the source named by their cite attribute.
+//
+// Preconditions:
+//   - Runs before convertLinks, so the source link is resolved and
+//     cleaned like any other link
+//
+// Postconditions:
+//   - <q cite="url">text</q> becomes "text" followed by a [source](url) link
+//   - <q> without a cite URL becomes "text"
+func convertQuotes(s string) string {
+	return reQuote.ReplaceAllStringFunc(s, func(match string) string {
+		m := reQuote.FindStringSubmatch(match)
+		quoted := `"` + m[2] + `"`
+		if cite, _ := tagAttr(m[1], "cite"); strings.TrimSpace(cite) != "" {
+			quoted += ` <a href="` + strings.TrimSpace(cite) + `">source</a>`
+		}
+		return quoted
+	})
+}
+
+// convertLinks converts HTML <a> tags to Markdown link syntax.
+//
+// Preconditions:
+//   - s may contain <a href="...">...</a> tags
+//   - opts is non-nil
+//
+// Invariants:
+//   - Only href attribute is extracted; other attributes are ignored
+//   - href may be double-quoted, single-quoted, or unquoted
+//   - href is rewritten according to opts by rewriteURL
+//
+// Postconditions:
+//   - <a href="url">text</a> becomes [text](url)
+//   - A link whose text is its absolute href becomes the autolink <url>,
+//     when opts.CollapseSelfLinks is set
+//   - With opts.ReferenceLinks, it becomes [text][label] instead, and the
+//     label definitions are appended to the end of s
+//   - <a> tags without href are left for cleanup
+func convertLinks(s string, opts *Options) string {
+	var refs *linkReferences
+	if opts.ReferenceLinks {
+		refs = newLinkReferences(opts.ReferenceLabelStyle)
+	}
+	s = reLink.ReplaceAllStringFunc(s, func(match string) string {
+		m := reLink.FindStringSubmatch(match)
+		href, ok := tagAttr(m[1], "href")
+		if !ok {
+			return match
+		}
+		dest := rewriteURL(href, opts)
+		if opts.CollapseSelfLinks && isSelfLink(m[2], dest) {
+			return escLT + dest + escGT
+		}
+		if refs != nil {
+			return "[" + m[2] + "][" + refs.label(m[2], dest) + "]"
+		}
+		return "[" + m[2] + "](" + dest + ")"
+	})
+	if refs != nil {
+		s += refs.definitions()
+	}
+	return s
+}
+
+// reAutolinkDest matches a URL that can be written as a CommonMark autolink:
+// an absolute URL with a scheme and no whitespace or angle brackets.
+var reAutolinkDest = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.\-]{1,31}:[^\s<>]+$`)
+
+// isSelfLink reports whether a link's text is its final destination, after
+// rewriting, and the destination can be an autolink. A link whose href was
+// resolved or cleaned is not a self link, since collapsing it would change
+// its visible text.
+func isSelfLink(text, dest string) bool {
+	return strings.TrimSpace(text) == dest && reAutolinkDest.MatchString(dest)
+}
+
+// convertImages converts HTML <img> tags to Markdown image syntax.
+//
+// Preconditions:
+//   - s may contain <img> tags with src and optional alt attributes
+//   - opts is non-nil
+//
+// Invariants:
+//   - Attributes are read regardless of order
+//   - Attribute values may be double-quoted, single-quoted, or unquoted
+//   - src is rewritten according to opts by rewriteURL
+//
+// Postconditions:
+//   - <img src="url" alt="text"> becomes ![text](url)
+//   - <img src="url"> becomes ![](url)
+//   - A non-empty title becomes ![text](url "title"), with " escaped
+//   - With opts.ImagesAsHTML, an <img> tag with selected attributes is emitted instead
+//   - Images with a data: URI src are kept, dropped, or given a
+//     placeholder src according to opts.DataURIImages
+//   - <img> tags without src are left for cleanup
+func convertImages(s string, opts *Options) string {
+	return reImg.ReplaceAllStringFunc(s, func(match string) string {
+		attrs := reImg.FindStringSubmatch(match)[1]
+		src, ok := tagAttr(attrs, "src")
+		if !ok {
+			return match
+		}
+		if isDataURI(src) {
+			switch opts.DataURIImages {
+			case DataURIStrip:
+				return ""
+			case DataURIPlaceholder:
+				src = dataImagePlaceholder
+			}
+		}
+		alt, _ := tagAttr(attrs, "alt")
+		if opts.ImagesAsHTML {
+			return imageHTML(rewriteURL(src, opts), attrs, opts)
+		}
+		dest := rewriteURL(src, opts)
+		if title, _ := tagAttr(attrs, "title"); strings.TrimSpace(title) != "" {
+			dest += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+		}
+		return "![" + alt + "](" + dest + ")"
+	})
+}
+
+// isDataURI reports whether src is an inline data: URI.
+func isDataURI(src string) bool {
+	src = strings.TrimSpace(src)
+	return len(src) >= 5 && strings.EqualFold(src[:5], "data:")
+}
+
+// imageHTML renders an <img> tag that survives cleanup, keeping src, alt,
+// and the attributes listed in opts.ImageKeepAttrs.
 //
 // Preconditions:
-//   - s may contain <hr> or <hr/> tags
+//   - src is the rewritten image URL
+//   - attrs is the attribute text of the original <img> tag
 //
 // Invariants:
-//   - Both self-closing and non-self-closing forms are handled
+//   - Attributes are written in the order src, alt, then ImageKeepAttrs
+//   - Attributes missing from the original tag are omitted
 //
 // Postconditions:
-//   - <hr> becomes "---" surrounded by blank lines
-func convertHorizontalRules(s string) string {
-	return reHr.ReplaceAllString(s, "\n\n---\n\n")
+//   - Returns an <img> tag written with escape placeholders
+func imageHTML(src, attrs string, opts *Options) string {
+	keep := opts.ImageKeepAttrs
+	if len(keep) == 0 {
+		keep = defaultImageKeepAttrs
+	}
+	var sb strings.Builder
+	sb.WriteString(escLT + `img src="` + src + `"`)
+	for _, name := range append([]string{"alt"}, keep...) {
+		if v, ok := tagAttr(attrs, name); ok {
+			sb.WriteString(" " + strings.ToLower(name) + `="` + v + `"`)
+		}
+	}
+	sb.WriteString(escGT)
+	return sb.String()
 }
 
-// convertLists converts both unordered and ordered HTML lists to Markdown.
+// tagAttr returns the value of the named attribute in the attribute text
+// of a tag.
 //
 // Preconditions:
-//   - s may contain <ul> and/or <ol> tags with nested <li> items
+//   - attrs is the text between the tag name and the closing >
 //
 // Invariants:
-//   - Unordered lists are processed before ordered lists
+//   - Attribute names are matched case-insensitively
+//   - Values may be double-quoted, single-quoted, or unquoted
 //
 // Postconditions:
-//   - <ul> lists become "- item" format
-//   - <ol> lists become "1. item" format with sequential numbering
-func convertLists(s string) string {
-	// Unordered lists
-	s = convertUnorderedLists(s)
-	// Ordered lists
-	s = convertOrderedLists(s)
-	return s
+//   - Returns the first matching value and true if the attribute exists
+//   - Returns "" and false otherwise
+func tagAttr(attrs, name string) (string, bool) {
+	for _, m := range reAttr.FindAllStringSubmatch(attrs, -1) {
+		if strings.EqualFold(m[1], name) {
+			return m[2] + m[3] + m[4], true
+		}
+	}
+	return "", false
+}
+
+// hasAttr reports whether attrs contains the named attribute, with or
+// without a value, as for boolean attributes such as reversed and checked.
+func hasAttr(attrs, name string) bool {
+	for _, m := range reAttrName.FindAllStringSubmatch(attrs, -1) {
+		if strings.EqualFold(m[1], name) {
+			return true
+		}
+	}
+	return false
 }
 
-// convertUnorderedLists converts HTML <ul> tags to Markdown unordered lists.
+// convertStyledSpans converts <span> tags styled as bold or italic via CSS
+// to Markdown emphasis.
 //
 // Preconditions:
-//   - s may contain <ul> tags
+//   - s may contain <span style="..."> tags
 //
 // Invariants:
-//   - Delegates item processing to convertListItems
+//   - font-weight of bold, bolder, or 700 and above counts as bold
+//   - font-style of italic or oblique counts as italic
 //
 // Postconditions:
-//   - Each <li> becomes "- item"
-//   - List is surrounded by blank lines
-func convertUnorderedLists(s string) string {
-	return reUl.ReplaceAllStringFunc(s, func(match string) string {
-		inner := reUl.FindStringSubmatch(match)[1]
-		return "\n\n" + convertListItems(inner, false) + "\n\n"
+//   - Bold spans are wrapped in **, italic spans in *, both in ***
+//   - Spans without emphasis styles are left unchanged
+func convertStyledSpans(s string) string {
+	return reStyledSpan.ReplaceAllStringFunc(s, func(match string) string {
+		m := reStyledSpan.FindStringSubmatch(match)
+		style := parseStyle(m[1])
+		marker := ""
+		if isBoldWeight(style["font-weight"]) {
+			marker += "**"
+		}
+		if fs := style["font-style"]; fs == "italic" || fs == "oblique" {
+			marker += "*"
+		}
+		if marker == "" {
+			return match
+		}
+		return marker + m[2] + marker
 	})
 }
 
-// convertOrderedLists converts HTML <ol> tags to Markdown ordered lists.
+// isBoldWeight reports whether a CSS font-weight value renders as bold.
+func isBoldWeight(weight string) bool {
+	switch weight {
+	case "bold", "bolder":
+		return true
+	}
+	n, err := strconv.Atoi(weight)
+	return err == nil && n >= 700
+}
+
+// parseStyle parses an inline CSS style attribute into lowercase
+// property/value pairs.
 //
 // Preconditions:
-//   - s may contain <ol> tags
+//   - style is the raw value of a style attribute
 //
 // Invariants:
-//   - Delegates item processing to convertListItems
+//   - Declarations without a colon are ignored
+//   - A trailing !important is removed from values
 //
 // Postconditions:
-//   - Each <li> becomes "N. item" with sequential numbering starting from 1
-//   - List is surrounded by blank lines
-func convertOrderedLists(s string) string {
-	return reOl.ReplaceAllStringFunc(s, func(match string) string {
-		inner := reOl.FindStringSubmatch(match)[1]
-		return "\n\n" + convertListItems(inner, true) + "\n\n"
-	})
+//   - Returns a map from property name to trimmed value
+//   - Later declarations override earlier ones, as in CSS
+func parseStyle(style string) map[string]string {
+	props := make(map[string]string)
+	for decl := range strings.SplitSeq(style, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		value = strings.TrimSpace(strings.TrimSuffix(value, "!important"))
+		props[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return props
 }
 
-// convertListItems extracts and formats list items from HTML <li> tags.
+// convertBold converts HTML <strong> and <b> tags to Markdown bold syntax.
 //
 // Preconditions:
-//   - s contains the inner content of a <ul> or <ol> tag
-//   - ordered indicates whether to use numbered or bulleted format
+//   - s may contain <strong> or <b> tags
 //
 // Invariants:
-//   - Nested <p> tags within list items are removed
-//   - Items are numbered sequentially starting from 1 for ordered lists
+//   - Both <strong> and <b> are treated equivalently
+//   - Redundant nesting such as <b><strong>x</strong></b> is flattened first
+//   - Adjacent tags such as <b>a</b><b>b</b> are merged, since "**a****b**"
+//     does not parse as two bold spans
 //
 // Postconditions:
-//   - Returns newline-separated list items
-//   - Each item is prefixed with "- " (unordered) or "N. " (ordered)
-func convertListItems(s string, ordered bool) string {
-	matches := reLi.FindAllStringSubmatch(s, -1)
-	var items []string
-	for i, match := range matches {
-		content := strings.TrimSpace(match[1])
-		// Remove nested p tags
-		content = rePTag.ReplaceAllString(content, "")
-		content = strings.TrimSpace(content)
-		if ordered {
-			items = append(items, strconv.Itoa(i+1)+". "+content)
-		} else {
-			items = append(items, "- "+content)
-		}
-	}
-	return strings.Join(items, "\n")
+//   - Content is wrapped in ** markers, or in <strong> tags where the
+//     markers could not open or close (see wrapEmphasis)
+func convertBold(s string) string {
+	s = flattenNestedTags(s, reBoldTag)
+	s = mergeAdjacentTags(s, reBoldTag)
+	return wrapEmphasis(s, reBold, "**", "strong")
 }
 
-// convertTables converts HTML <table> tags to Markdown tables.
+// convertItalic converts HTML <em> and <i> tags to Markdown italic syntax.
 //
 // Preconditions:
-//   - s may contain <table> tags with <tr>, <th>, and <td> elements
+//   - s may contain <em> or <i> tags
 //
 // Invariants:
-//   - Delegates row processing to convertTableContent
+//   - Both <em> and <i> are treated equivalently
+//   - Redundant nesting such as <em><i>x</i></em> is flattened first
 //
 // Postconditions:
-//   - Table is converted to pipe-delimited Markdown format
-//   - Table is surrounded by blank lines
-func convertTables(s string) string {
-	return reTable.ReplaceAllStringFunc(s, func(match string) string {
-		inner := reTable.FindStringSubmatch(match)[1]
-		return "\n\n" + convertTableContent(inner) + "\n\n"
-	})
+//   - Content is wrapped in * markers, or in <em> tags where the markers
+//     could not open or close (see wrapEmphasis)
+func convertItalic(s string) string {
+	s = flattenNestedTags(s, reItalicTag)
+	s = mergeAdjacentTags(s, reItalicTag)
+	return wrapEmphasis(s, reItalic, "*", "em")
 }
 
-// convertTableContent processes the inner content of an HTML table.
+// convertStrikethrough converts HTML <del>, <s>, and <strike> tags to
+// Markdown strikethrough syntax.
 //
 // Preconditions:
-//   - s contains <tr> rows with <th> and/or <td> cells
+//   - s may contain <del>, <s>, or <strike> tags
 //
 // Invariants:
-//   - First row is treated as header
-//   - Separator row is inserted after header
+//   - All three tags are treated equivalently
+//   - Redundant nesting such as <del><s>x</s></del> is flattened first
 //
 // Postconditions:
-//   - Returns pipe-delimited table with header separator
-//   - Empty rows are skipped
-//   - Returns empty string if no valid rows found
-func convertTableContent(s string) string {
-	// Extract rows
-	rows := reRow.FindAllStringSubmatch(s, -1)
-
-	if len(rows) == 0 {
-		return ""
-	}
-
-	var result []string
-	headerWritten := false
-
-	for _, row := range rows {
-		cells := extractCells(row[1])
-		if len(cells) == 0 {
-			continue
-		}
-
-		line := "| " + strings.Join(cells, " | ") + " |"
-		result = append(result, line)
-
-		// Add separator after first row (header)
-		if !headerWritten {
-			var sep strings.Builder
-			sep.WriteString("|")
-			for range cells {
-				sep.WriteString(" --- |")
-			}
-			result = append(result, sep.String())
-			headerWritten = true
-		}
-	}
-
-	return strings.Join(result, "\n")
+//   - Content is wrapped in ~~ markers, or in <del> tags where the markers
+//     could not open or close (see wrapEmphasis)
+func convertStrikethrough(s string) string {
+	s = flattenNestedTags(s, reStrikeTag)
+	s = mergeAdjacentTags(s, reStrikeTag)
+	return wrapEmphasis(s, reStrike, "~~", "del")
 }
 
-// extractCells extracts cell contents from an HTML table row.
+// wrapEmphasis replaces each match of re, whose second group is the
+// content, with the content wrapped in marker.
 //
-// Preconditions:
-//   - row contains <th> and/or <td> elements
+// Markers are always * rather than _, since CommonMark lets * emphasis
+// open and close inside a word, as in foo*bar*baz, while _ never can.
+// A * run still fails when it sits between a letter and punctuation, as
+// in foo*"bar"*baz, so such spans are written as raw <tag> HTML instead.
+//
+// Whitespace just inside the tags is moved outside the markers, since a
+// marker followed or preceded by whitespace cannot open or close, so
+// "<strong> bold </strong>" becomes " **bold** ".
 //
 // Invariants:
-//   - <th> cells are extracted before <td> cells
-//   - Cell content is trimmed of whitespace
+//   - The text around each match is not modified
 //
 // Postconditions:
-//   - Returns slice of cell contents in order
-//   - Returns empty slice if no cells found
-func extractCells(row string) []string {
-	// Try th first, then td
-	var cells []string
-	thMatches := reTh.FindAllStringSubmatch(row, -1)
-	tdMatches := reTd.FindAllStringSubmatch(row, -1)
+//   - Every span renders as emphasis in CommonMark and GFM
+//   - A span holding only whitespace is reduced to the whitespace
+func wrapEmphasis(s string, re *regexp.Regexp, marker, tag string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		inner := s[m[4]:m[5]]
+		content := strings.TrimLeftFunc(inner, unicode.IsSpace)
+		lead := inner[:len(inner)-len(content)]
+		content = strings.TrimRightFunc(content, unicode.IsSpace)
+		trail := inner[len(lead)+len(content):]
 
-	for _, m := range thMatches {
-		cells = append(cells, strings.TrimSpace(m[1]))
-	}
-	for _, m := range tdMatches {
-		cells = append(cells, strings.TrimSpace(m[1]))
+		sb.WriteString(s[last:m[0]] + lead)
+		last = m[1]
+		if content == "" {
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(s[:m[0]])
+		if lead != "" {
+			before = ' '
+		}
+		after, _ := utf8.DecodeRuneInString(s[m[1]:])
+		if trail != "" {
+			after = ' '
+		}
+		first, _ := utf8.DecodeRuneInString(content)
+		final, _ := utf8.DecodeLastRuneInString(content)
+
+		if flanks(before, first) && flanks(after, final) {
+			sb.WriteString(marker + content + marker)
+		} else {
+			sb.WriteString(escapedTag(tag) + content + escapedTag("/"+tag))
+		}
+		sb.WriteString(trail)
 	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
 
-	return cells
+// flanks reports whether a * run between outside and inside can open or
+// close emphasis, following the CommonMark flanking rules: it cannot when
+// inside is punctuation and outside is a word character. The runes may be
+// utf8.RuneError at the start or end of the text, which counts as space.
+func flanks(outside, inside rune) bool {
+	return !isMarkdownPunct(inside) || outside == utf8.RuneError ||
+		unicode.IsSpace(outside) || isMarkdownPunct(outside)
 }
 
-// convertLinks converts HTML <a> tags to Markdown link syntax.
-//
-// Preconditions:
-//   - s may contain <a href="...">...</a> tags
+// isMarkdownPunct reports whether r is punctuation or a symbol in the
+// sense of CommonMark's flanking rules.
+func isMarkdownPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// fixMisnestedTags repairs overlapping formatting tags such as
+// "<b>a <i>b</b> c</i>" into properly nested ones, as the HTML parser's
+// adoption agency algorithm does for <b>, <i>, and the like.
 //
 // Invariants:
-//   - Only href attribute is extracted; other attributes are ignored
+//   - Tags are matched by exact name, so <b> is not closed by </strong>
+//   - A closing tag without a matching open tag is kept unchanged
+//   - Tags that are never closed are kept unchanged
 //
 // Postconditions:
-//   - <a href="url">text</a> becomes [text](url)
-func convertLinks(s string) string {
-	return reLink.ReplaceAllString(s, "[$2]($1)")
+//   - A closing tag first closes the tags opened after its match, then
+//     reopens them with their original attributes after any whitespace
+//     that follows, so "<b>a <i>b</b> c</i>" becomes
+//     "<b>a <i>b</i></b> <i>c</i>"
+func fixMisnestedTags(s string) string {
+	type openTag struct{ name, tag string }
+	var stack []openTag
+	var sb strings.Builder
+	last := 0
+	for _, m := range reFormatTag.FindAllStringSubmatchIndex(s, -1) {
+		name := strings.ToLower(s[m[4]:m[5]])
+		if m[3] == m[2] {
+			stack = append(stack, openTag{name, s[m[0]:m[1]]})
+			continue
+		}
+		j := len(stack) - 1
+		for j >= 0 && stack[j].name != name {
+			j--
+		}
+		if j < 0 {
+			continue
+		}
+		if j == len(stack)-1 {
+			stack = stack[:j]
+			continue
+		}
+		sb.WriteString(s[last:m[0]])
+		after := stack[j+1:]
+		for k := len(after) - 1; k >= 0; k-- {
+			sb.WriteString("</" + after[k].name + ">")
+		}
+		// Whitespace after the closing tag stays outside the reopened tags
+		// so that the markers of their content can open
+		rest := s[m[1]:]
+		space := rest[:len(rest)-len(strings.TrimLeft(rest, " \t\n"))]
+		sb.WriteString(s[m[0]:m[1]] + space)
+		for _, t := range after {
+			sb.WriteString(t.tag)
+		}
+		last = m[1] + len(space)
+		stack = append(stack[:j], after...)
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
 }
 
-// convertImages converts HTML <img> tags to Markdown image syntax.
+// flattenNestedTags removes tags that are nested inside another tag of the
+// same group, so that equivalent markup produces a single pair of markers.
 //
 // Preconditions:
-//   - s may contain <img> tags with src and optional alt attributes
+//   - reTag matches both opening and closing tags of one group,
+//     e.g. <b>, </b>, <strong>, and </strong>
 //
 // Invariants:
-//   - Handles both src-before-alt and alt-before-src attribute orders
-//   - Handles images with src only (no alt)
+//   - Text between tags is never modified
+//   - Unbalanced closing tags at depth zero are kept
 //
 // Postconditions:
-//   - <img src="url" alt="text"> becomes ![text](url)
-//   - <img src="url"> becomes ![](url)
-func convertImages(s string) string {
-	// Handle both self-closing and regular img tags
-	s = reImgSrcAlt.ReplaceAllString(s, "![$2]($1)")
-
-	// Handle img with src only (no alt or alt first)
-	s = reImgAltSrc.ReplaceAllString(s, "![$1]($2)")
-
-	// Handle img with src only
-	s = reImgSrc.ReplaceAllString(s, "![]($1)")
-
-	return s
+//   - Only the outermost opening and closing tag of each nest remain
+func flattenNestedTags(s string, reTag *regexp.Regexp) string {
+	depth := 0
+	return reTag.ReplaceAllStringFunc(s, func(tag string) string {
+		if strings.HasPrefix(tag, "</") {
+			if depth == 0 {
+				return tag
+			}
+			depth--
+			if depth == 0 {
+				return tag
+			}
+			return ""
+		}
+		depth++
+		if depth == 1 {
+			return tag
+		}
+		return ""
+	})
 }
 
-// convertBold converts HTML <strong> and <b> tags to Markdown bold syntax.
+// mergeAdjacentTags removes a closing tag and the opening tag of the same
+// group that directly follows it, so adjacent spans become one span.
 //
 // Preconditions:
-//   - s may contain <strong> or <b> tags
+//   - reTag matches both opening and closing tags of one group
+//   - Nested tags of the group have been flattened
 //
 // Invariants:
-//   - Both <strong> and <b> are treated equivalently
+//   - Whitespace between the merged tags is kept inside the span
 //
 // Postconditions:
-//   - Content is wrapped in ** markers
-func convertBold(s string) string {
-	return reBold.ReplaceAllString(s, "**$2**")
+//   - No closing tag of the group is followed, after optional whitespace,
+//     by an opening tag of the group
+func mergeAdjacentTags(s string, reTag *regexp.Regexp) string {
+	locs := reTag.FindAllStringIndex(s, -1)
+	var sb strings.Builder
+	pos := 0
+	for i := 0; i+1 < len(locs); i++ {
+		closing, opening := locs[i], locs[i+1]
+		if !strings.HasPrefix(s[closing[0]:], "</") || strings.HasPrefix(s[opening[0]:], "</") ||
+			strings.TrimSpace(s[closing[1]:opening[0]]) != "" {
+			continue
+		}
+		sb.WriteString(s[pos:closing[0]])
+		sb.WriteString(s[closing[1]:opening[0]])
+		pos = opening[1]
+		i++
+	}
+	sb.WriteString(s[pos:])
+	return sb.String()
 }
 
-// convertItalic converts HTML <em> and <i> tags to Markdown italic syntax.
+// convertKeyboard converts HTML <kbd> and <tt> tags to <code> tags so that
+// keyboard input and legacy monospace text are rendered as inline code by
+// convertInlineCode.
 //
 // Preconditions:
-//   - s may contain <em> or <i> tags
+//   - s may contain <kbd> tags, possibly nested for key combinations
 //
 // Invariants:
-//   - Both <em> and <i> are treated equivalently
+//   - Nested <kbd>, <tt>, and <code> tags are flattened to the outermost tag,
+//     so <kbd><kbd>Ctrl</kbd>+<kbd>C</kbd></kbd> becomes one code span
 //
 // Postconditions:
-//   - Content is wrapped in * markers
-func convertItalic(s string) string {
-	return reItalic.ReplaceAllString(s, "*$2*")
+//   - <kbd>x</kbd> and <tt>x</tt> become <code>x</code>
+//   - Sequences such as <kbd>Ctrl</kbd>+<kbd>C</kbd> stay separate spans
+func convertKeyboard(s string) string {
+	s = flattenNestedTags(s, reCodeLikeTag)
+	return reMonoTag.ReplaceAllString(s, "<${1}code>")
 }
 
 // convertInlineCode converts HTML <code> tags to Markdown inline code syntax.
@@ -547,19 +2582,46 @@ func convertItalic(s string) string {
 //   - Angle brackets are temporarily escaped to survive cleanup
 //
 // Postconditions:
-//   - Content is wrapped in backticks
+//   - Content is wrapped in backticks by codeSpan
 //   - HTML entities like &lt; are converted to actual characters
+//   - Directly adjacent code spans are separated by a space, since their
+//     touching backticks would otherwise parse as a single span
 func convertInlineCode(s string) string {
+	s = reAdjacentCode.ReplaceAllString(s, "</code> $1")
 	return reInlineCode.ReplaceAllStringFunc(s, func(match string) string {
 		inner := reInlineCode.FindStringSubmatch(match)[1]
 		inner = decodeHTMLEntities(inner)
 		// Escape any remaining angle brackets to prevent cleanup from removing them
-		inner = strings.ReplaceAll(inner, "<", escLT)
-		inner = strings.ReplaceAll(inner, ">", escGT)
-		return "`" + inner + "`"
+		inner = escapeAngles(inner)
+		return codeSpan(inner)
 	})
 }
 
+// codeSpan wraps code in a backtick delimiter that cannot collide with
+// backticks inside it, following CommonMark.
+//
+// Postconditions:
+//   - The delimiter is one backtick longer than the longest run of
+//     backticks in code
+//   - A space pads each side when code starts or ends with a backtick,
+//     so the delimiter is not extended
+func codeSpan(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
 // convertLineBreaks converts HTML <br> tags to Markdown line breaks.
 //
 // Preconditions:
@@ -580,13 +2642,66 @@ func convertLineBreaks(s string) string {
 //   - s may contain HTML entities
 //
 // Invariants:
-//   - Only predefined entities are decoded
-//   - Unknown entities are left unchanged
+//   - Only numeric references and the named entities in htmlEntities are
+//     decoded; unknown entities are left unchanged
+//   - As in the HTML parsing spec, a missing semicolon is tolerated for
+//     numeric references and for every named entity except &apos;, unless
+//     the name is followed by a letter, digit, or =, as in "?a=1&amp=2"
+//   - Numeric references to NUL, surrogates, or beyond U+10FFFF decode
+//     to U+FFFD
 //
 // Postconditions:
-//   - &lt; &gt; &amp; &quot; &#39; &apos; &nbsp; are decoded
+//   - &lt; &gt; &amp; &quot; &apos; &nbsp; and &#169; &#xA9; &#xa9; style
+//     references are decoded
 func decodeHTMLEntities(s string) string {
-	return htmlEntityReplacer.Replace(s)
+	if strings.IndexByte(s, '&') < 0 {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	last := 0
+	for _, m := range reEntity.FindAllStringSubmatchIndex(s, -1) {
+		text, ok := entityText(s, m)
+		if !ok {
+			continue
+		}
+		sb.WriteString(s[last:m[0]] + text)
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// entityText returns the text of the reEntity match m in s, and false if
+// the match must be left unchanged.
+func entityText(s string, m []int) (string, bool) {
+	semicolon := m[9] > m[8]
+	switch {
+	case m[2] >= 0:
+		return codePointText(s[m[2]:m[3]], 16), true
+	case m[4] >= 0:
+		return codePointText(s[m[4]:m[5]], 10), true
+	}
+	name := s[m[6]:m[7]]
+	if !semicolon {
+		if name == "apos" {
+			return "", false
+		}
+		if next, _ := utf8.DecodeRuneInString(s[m[1]:]); next == '=' || unicode.IsLetter(next) || unicode.IsDigit(next) {
+			return "", false
+		}
+	}
+	return htmlEntities[name], true
+}
+
+// codePointText returns the character whose code point is digits in base,
+// or U+FFFD if it is not a valid scalar value other than NUL.
+func codePointText(digits string, base int) string {
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || n == 0 || n > unicode.MaxRune || n >= 0xD800 && n <= 0xDFFF {
+		return "\uFFFD"
+	}
+	return string(rune(n))
 }
 
 // cleanupOutput performs final cleanup on the converted Markdown output.
@@ -608,12 +2723,14 @@ func cleanupOutput(s string) string {
 	// Remove remaining HTML tags
 	s = reHtmlTag.ReplaceAllString(s, "")
 
-	// Restore escaped angle brackets in code
+	// Restore escaped angle brackets in code and list indentation
 	s = strings.ReplaceAll(s, escLT, "<")
 	s = strings.ReplaceAll(s, escGT, ">")
+	s = strings.ReplaceAll(s, escIndent, " ")
 
 	// Decode remaining entities
 	s = decodeHTMLEntities(s)
+	s = strings.ReplaceAll(s, escAmp, "&")
 
 	// Normalize multiple newlines to max 2
 	s = reMultiNewline.ReplaceAllString(s, "\n\n")
@@ -637,3 +2754,237 @@ func cleanupOutput(s string) string {
 
 	return sb.String()
 }
+
+// collapseProseWhitespace collapses runs of spaces and tabs in the prose
+// of converted Markdown to a single space.
+//
+// Preconditions:
+//   - s has been processed by cleanupOutput, with indented code lines
+//     still prefixed by escCodeIndent
+//
+// Postconditions:
+//   - No run of two or more spaces or tabs remains in prose
+func collapseProseWhitespace(s string) string {
+	return mapProse(s, func(text string) string {
+		return reWhitespace.ReplaceAllString(text, " ")
+	})
+}
+
+// stripInvisibleChars removes the characters in invisibleCharReplacer
+// from the prose of converted Markdown.
+//
+// Preconditions:
+//   - s has been processed by cleanupOutput, with indented code lines
+//     still prefixed by escCodeIndent
+func stripInvisibleChars(s string) string {
+	if !strings.ContainsAny(s, "\u00ad\u200b\u2060\ufeff") {
+		return s
+	}
+	return mapProse(s, invisibleCharReplacer.Replace)
+}
+
+// mapProse applies fn to the prose of converted Markdown.
+//
+// Preconditions:
+//   - s has been processed by cleanupOutput, with indented code lines
+//     still prefixed by escCodeIndent
+//
+// Invariants:
+//   - Lines inside ``` fences and indented code lines are not modified
+//   - Code spans are copied verbatim
+//   - Leading indentation and the two trailing spaces of a hard break
+//     are not passed to fn
+func mapProse(s string, fn func(string) string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		content := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(content, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || strings.HasPrefix(line, escCodeIndent) {
+			continue
+		}
+
+		indent := line[:len(line)-len(content)]
+		hardBreak := ""
+		if strings.HasSuffix(content, "  ") && strings.TrimSpace(content) != "" {
+			content = strings.TrimRight(content, " ")
+			hardBreak = "  "
+		}
+		lines[i] = indent + mapOutsideCodeSpans(content, fn) + hardBreak
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mapOutsideCodeSpans applies fn to the text of line outside code spans.
+// A code span opens with a run of backticks and closes at the next run of
+// the same length; an unmatched run is treated as text.
+func mapOutsideCodeSpans(line string, fn func(string) string) string {
+	var sb strings.Builder
+	sb.Grow(len(line))
+	text := 0
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for i < len(line) && line[i] == '`' {
+			i++
+		}
+		fence := line[run:i]
+		end := indexBacktickRun(line[i:], len(fence))
+		if end < 0 {
+			continue
+		}
+		sb.WriteString(fn(line[text:run]))
+		closeAt := i + end + len(fence)
+		sb.WriteString(line[run:closeAt])
+		i, text = closeAt, closeAt
+	}
+	sb.WriteString(fn(line[text:]))
+	return sb.String()
+}
+
+// indexBacktickRun returns the index in s of the first run of exactly n
+// backticks, or -1 if there is none.
+func indexBacktickRun(s string, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] == '`' {
+			i++
+		}
+		if i-start == n {
+			return start
+		}
+	}
+	return -1
+}
+
+// truncateMarkdown shortens s to at most limit characters, ending with an
+// ellipsis.
+//
+// Preconditions:
+//   - limit is greater than 0
+//
+// Invariants:
+//   - s is returned unchanged if it fits
+//   - The cut never falls inside a link, image, emphasis, code span, or
+//     fenced code block; it moves to before the construct instead
+//   - The cut falls on whitespace, so words are not split; a single word
+//     longer than limit is dropped entirely
+//
+// Postconditions:
+//   - The result, including the trailing "…", has at most limit runes
+func truncateMarkdown(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	cut := 0
+	for range limit - 1 {
+		_, size := utf8.DecodeRuneInString(s[cut:])
+		cut += size
+	}
+	for _, span := range markdownSpans(s) {
+		if span[0] < cut && cut < span[1] {
+			cut = span[0]
+			break
+		}
+	}
+	if cut < len(s) && !isSpaceByte(s[cut]) {
+		cut = max(strings.LastIndexAny(s[:cut], " \t\n"), 0)
+	}
+	return strings.TrimRight(s[:cut], " \t\n") + "…"
+}
+
+// markdownSpans returns the byte ranges of the inline and reference links,
+// images, emphasis, and code in s, in order of their start. Fenced code
+// blocks are found as code spans delimited by ``` runs.
+func markdownSpans(s string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] == '`' {
+			i++
+		}
+		fence := i - start
+		if end := indexBacktickRun(s[i:], fence); end >= 0 {
+			i += end + fence
+			spans = append(spans, [2]int{start, i})
+		}
+	}
+	for _, loc := range reMarkdownLink.FindAllStringIndex(s, -1) {
+		spans = append(spans, [2]int{loc[0], loc[1]})
+	}
+	for _, loc := range reReferenceLink.FindAllStringIndex(s, -1) {
+		spans = append(spans, [2]int{loc[0], loc[1]})
+	}
+	spans = append(spans, emphasisSpans(s, spans)...)
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+	return spans
+}
+
+// emphasisSpans returns the byte ranges of the emphasis in s, from the
+// opening delimiter run to its closing run. Delimiters are runs of *, _,
+// or ~; inside the code spans in skip they are ignored.
+//
+// Invariants:
+//   - A run opens when followed by a non-space and closes when preceded
+//     by a non-space and an open run of the same character exists
+//   - A closing run may close several open runs, as *** closes ** and *
+//   - A delimiter escaped with a backslash is ignored
+func emphasisSpans(s string, skip [][2]int) [][2]int {
+	type opener struct {
+		ch         byte
+		start, len int
+	}
+	var stack []opener
+	var spans [][2]int
+	for i := 0; i < len(s); {
+		if j := slices.IndexFunc(skip, func(sp [2]int) bool { return sp[0] <= i && i < sp[1] && s[sp[0]] == '`' }); j >= 0 {
+			i = skip[j][1]
+			continue
+		}
+		ch := s[i]
+		if ch != '*' && ch != '_' && ch != '~' || i > 0 && s[i-1] == '\\' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] == ch {
+			i++
+		}
+		n := i - start
+		canClose := start > 0 && !isSpaceByte(s[start-1])
+		canOpen := i < len(s) && !isSpaceByte(s[i])
+		for canClose && n > 0 && len(stack) > 0 && stack[len(stack)-1].ch == ch {
+			top := &stack[len(stack)-1]
+			used := min(top.len, n)
+			top.len -= used
+			n -= used
+			spans = append(spans, [2]int{top.start, i})
+			if top.len == 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if n > 0 && canOpen {
+			stack = append(stack, opener{ch: ch, start: start, len: n})
+		}
+	}
+	return spans
+}
+
+// isSpaceByte reports whether b is a space, tab, or newline.
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n'
+}
@@ -54,18 +54,51 @@ func BenchmarkConvert(b *testing.B) {
 }
 
 func BenchmarkConvert_LargeDocument(b *testing.B) {
+	input := largeDocument()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		Convert(string(input))
+	}
+}
+
+func BenchmarkConvertBytes_LargeDocument(b *testing.B) {
+	input := largeDocument()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ConvertBytes(input)
+	}
+}
+
+func BenchmarkConvert_PlainText(b *testing.B) {
+	input := strings.Repeat("Plain text without any markup, as read from a .txt file.\n\n", 100)
+
+	b.Run("fast path", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			Convert(input)
+		}
+	})
+	b.Run("full pipeline", func(b *testing.B) {
+		opts := &Options{}
+		b.ReportAllocs()
+		for b.Loop() {
+			finishMarkdown(convertTags(input, opts, nil), opts, nil)
+		}
+	})
+}
+
+// largeDocument returns the input of the large document benchmarks as
+// bytes, as read from a file or request body.
+func largeDocument() []byte {
 	base := `<h2>Section</h2><p>Paragraph with <strong>bold</strong> and <em>italic</em> text.</p>`
 	var sb strings.Builder
 	sb.WriteString("<h1>Document Title</h1>")
 	for range 100 {
 		sb.WriteString(base)
 	}
-	input := sb.String()
-
-	b.ResetTimer()
-	for b.Loop() {
-		Convert(input)
-	}
+	return []byte(sb.String())
 }
 
 func BenchmarkConvertInternal(b *testing.B) {
@@ -79,17 +112,17 @@ func BenchmarkConvertInternal(b *testing.B) {
 	}{
 		{
 			name: "convertHeadings",
-			fn:   convertHeadings,
+			fn:   func(s string) string { return convertHeadings(s, &Options{}) },
 			args: args{html: "<h1>Title</h1><h2>Subtitle</h2><h3>Section</h3>"},
 		},
 		{
 			name: "convertLinks",
-			fn:   convertLinks,
+			fn:   func(s string) string { return convertLinks(s, &Options{}) },
 			args: args{html: `<a href="https://example.com">Link 1</a> and <a href="https://test.com">Link 2</a>`},
 		},
 		{
 			name: "convertTables",
-			fn:   convertTables,
+			fn:   func(s string) string { return convertTables(s, &Options{}, nil) },
 			args: args{html: `<table>
 		<tr><th>H1</th><th>H2</th><th>H3</th></tr>
 		<tr><td>A1</td><td>A2</td><td>A3</td></tr>
@@ -103,7 +136,7 @@ func BenchmarkConvertInternal(b *testing.B) {
 		},
 		{
 			name: "convertLists",
-			fn:   convertLists,
+			fn:   func(s string) string { return convertLists(s, &Options{}) },
 			args: args{html: "<ul><li>A</li><li>B</li></ul><ol><li>1</li><li>2</li></ol>"},
 		},
 	}
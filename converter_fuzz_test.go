@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzConvert(f *testing.F) {
+	seeds := []string{
+		"",
+		"<h1>Title</h1><p>Hello <strong>world</strong></p>",
+		"<ul><li>a<ul><li>b</li></ul></li></ul><ol start=\"3\"><li>c</li></ol>",
+		"<table><tr><th>A</th></tr><tr><td>x|y<br>z</td></tr></table>",
+		"<pre><code>a &lt; b\n\tc</code></pre><code>``</code>",
+		"<blockquote><p>q</p><hr></blockquote><details><summary>s</summary>d</details>",
+		"<p>\x00LT\x00script\x00GT\x00 \x00IN\x00 \x00CI\x00 \x00TOC\x00</p>",
+		"<div><div><div><p>deep</div></p></div>",
+		"</li></ul><li><ol></table><td>",
+		"<ruby>漢字<rt>かんじ</rt></ruby><!-- c --><span class=\"math\">\\(x\\)</span>",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	opts := Options{
+		Footnotes:          true,
+		GenerateTOC:        true,
+		KeepComments:       MarkdownLink,
+		CodeBlockStyle:     Indented,
+		PreserveMath:       true,
+		CollapseWhitespace: true,
+	}
+	f.Fuzz(func(t *testing.T, html string) {
+		for _, md := range []string{Convert(html), ConvertWithOptions(html, opts)} {
+			if strings.Contains(md, "\x00") {
+				t.Errorf("output contains a placeholder byte: %q", md)
+			}
+			if utf8.ValidString(html) && !utf8.ValidString(md) {
+				t.Errorf("output is not valid UTF-8: %q", md)
+			}
+		}
+	})
+}
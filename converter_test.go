@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
 )
 
 func TestConvert(t *testing.T) {
@@ -49,6 +50,16 @@ func TestConvert(t *testing.T) {
 			args: args{html: "<h6>Smallest</h6>"},
 			want: "###### Smallest",
 		},
+		{
+			name: "hgroupの場合に最初の見出しが主見出しで残りが斜体の副題になる",
+			args: args{html: "<hgroup><h1>Title</h1><h2>Subtitle</h2></hgroup><p>Body</p>"},
+			want: "# Title\n\n*Subtitle*\n\nBody",
+		},
+		{
+			name: "hgroup内のpの場合に斜体の副題になる",
+			args: args{html: "<hgroup><h2>Title</h2><p>Tagline</p></hgroup>"},
+			want: "## Title\n\n*Tagline*",
+		},
 		// 段落
 		{
 			name: "pタグの場合にテキストのみに変換される",
@@ -61,6 +72,16 @@ func TestConvert(t *testing.T) {
 			want: "First\n\nSecond",
 		},
 		// 強調
+		{
+			name: "隣接するstrongタグの場合にひとつの太字にまとめられる",
+			args: args{html: "<strong>a</strong><b>b</b> <strong>c</strong>"},
+			want: "**ab c**",
+		},
+		{
+			name: "隣接するemタグの場合にひとつの斜体にまとめられる",
+			args: args{html: "<em>a</em><em>b</em>"},
+			want: "*ab*",
+		},
 		{
 			name: "strongタグの場合に**で囲まれる",
 			args: args{html: "<strong>bold</strong>"},
@@ -81,12 +102,87 @@ func TestConvert(t *testing.T) {
 			args: args{html: "<i>italic</i>"},
 			want: "*italic*",
 		},
+		{
+			name: "delタグの場合に~~で囲まれる",
+			args: args{html: "<del>gone</del>"},
+			want: "~~gone~~",
+		},
+		{
+			name: "sタグの場合に~~で囲まれる",
+			args: args{html: "<s>gone</s>"},
+			want: "~~gone~~",
+		},
+		{
+			name: "delの中にsがある場合に取り消し線が二重にならない",
+			args: args{html: "<del><s>x</s></del>"},
+			want: "~~x~~",
+		},
+		{
+			name: "bの中にstrongがある場合に太字が二重にならない",
+			args: args{html: "<b><strong>x</strong> y</b>"},
+			want: "**x y**",
+		},
+		{
+			name: "emの中にiがある場合に斜体が二重にならない",
+			args: args{html: "<em>a <i>b</i></em>"},
+			want: "*a b*",
+		},
+		{
+			name: "brの後にbタグがある場合にbrが太字として扱われない",
+			args: args{html: "a<br>b <b>c</b>"},
+			want: "a  \nb **c**",
+		},
+		{
+			name: "単語の途中のemタグの場合に*で囲まれる",
+			args: args{html: "<p>un<em>believ</em>able</p>"},
+			want: "un*believ*able",
+		},
+		{
+			name: "単語の途中のstrongタグの場合に**で囲まれる",
+			args: args{html: "<p>foo<strong>bar</strong>baz</p>"},
+			want: "foo**bar**baz",
+		},
+		{
+			name: "単語に挟まれたemの内容が句読点で始まる場合にHTMLタグになる",
+			args: args{html: `<p>foo<em>"bar"</em>baz</p>`},
+			want: `foo<em>"bar"</em>baz`,
+		},
+		{
+			name: "文字に続くstrongの内容が括弧で始まる場合にHTMLタグになる",
+			args: args{html: "<p>これは<strong>「重要」</strong>です</p>"},
+			want: "これは<strong>「重要」</strong>です",
+		},
+		{
+			name: "空白に続くemの内容が句読点で始まる場合に*で囲まれる",
+			args: args{html: `<p>say <em>"hi"</em> now</p>`},
+			want: `say *"hi"* now`,
+		},
+		{
+			name: "spanタグの場合に取り消し線として扱われない",
+			args: args{html: "<span>plain</span>"},
+			want: "plain",
+		},
 		// リンク
 		{
 			name: "aタグの場合にMarkdownリンクに変換される",
 			args: args{html: `<a href="https://example.com">Example</a>`},
 			want: "[Example](https://example.com)",
 		},
+		{
+			name: "aタグのhrefが引用符なしの場合にMarkdownリンクに変換される",
+			args: args{html: `<a href=http://example.com/?a=b>x</a>`},
+			want: "[x](http://example.com/?a=b)",
+		},
+		{
+			name: "aタグのhrefがシングルクォートの場合にMarkdownリンクに変換される",
+			args: args{html: `<a class='c' href='/path'>x</a>`},
+			want: "[x](/path)",
+		},
+		{
+			name: "hrefのないaタグの場合にテキストのみになる",
+			args: args{html: `<a name="top">x</a>`},
+			want: "x",
+		},
 		// 画像
 		{
 			name: "imgタグでalt属性がある場合に画像記法に変換される",
@@ -98,6 +194,42 @@ func TestConvert(t *testing.T) {
 			args: args{html: `<img src="image.png">`},
 			want: "![](image.png)",
 		},
+		{
+			name: "imgタグのsrcとaltが引用符なしの場合に画像記法に変換される",
+			args: args{html: `<img src=image.png alt=pic>`},
+			want: "![pic](image.png)",
+		},
+		{
+			name: "imgタグのaltがsrcより前にある場合に画像記法に変換される",
+			args: args{html: `<img alt="An image" src="image.png"/>`},
+			want: "![An image](image.png)",
+		},
+		{
+			name: "imgタグの属性の間に他の属性がある場合に画像記法に変換される",
+			args: args{html: `<img class="x" alt="a" data-y="z" src="u">`},
+			want: "![a](u)",
+		},
+		{
+			name: "imgタグにdata-altやdata-srcがある場合に本来の属性が使われる",
+			args: args{html: `<img data-src="lazy.png" data-alt="no" width="10" src="real.png" alt="yes">`},
+			want: "![yes](real.png)",
+		},
+		{
+			name: "imgタグにtitle属性がある場合にタイトル付きの画像記法に変換される",
+			args: args{html: `<img title='Say "hi"' src="u.png" loading="lazy" alt="a">`},
+			want: `![a](u.png "Say \"hi\"")`,
+		},
+		// ルビ
+		{
+			name: "rubyタグの場合に読みが括弧で続く",
+			args: args{html: "<p><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>を読む</p>"},
+			want: "漢字(かんじ)を読む",
+		},
+		{
+			name: "rubyタグに複数のrtがある場合に文字ごとに読みが付く",
+			args: args{html: "<ruby><rb>漢</rb><rt>かん</rt><rb>字</rb><rt>じ</rt></ruby>"},
+			want: "漢(かん)字(じ)",
+		},
 		// コード
 		{
 			name: "codeタグの場合にバッククォートで囲まれる",
@@ -109,11 +241,149 @@ func TestConvert(t *testing.T) {
 			args: args{html: "<code>&lt;div&gt;</code>"},
 			want: "`<div>`",
 		},
+		{
+			name: "16進数の文字参照の場合に大文字小文字を問わずデコードされる",
+			args: args{html: "<p>&#xA9; &#xa9; &#XA9; &#169;</p>"},
+			want: "© © © ©",
+		},
+		{
+			name: "セミコロンのない文字参照の場合にデコードされる",
+			args: args{html: "<p>Fish &amp chips &#169 2024 &lt;b&gt</p>"},
+			want: "Fish & chips © 2024 <b>",
+		},
+		{
+			name: "セミコロンのない名前付き文字参照の後に英数字や等号が続く場合にそのまま残る",
+			args: args{html: "<p>?a=1&amp=2&ampx &apos</p>"},
+			want: "?a=1&amp=2&ampx &apos",
+		},
+		{
+			name: "無効な数値文字参照の場合に置換文字になる",
+			args: args{html: "<p>&#0; &#xD800; &#x110000; &#99999999999;</p>"},
+			want: "\uFFFD \uFFFD \uFFFD \uFFFD",
+		},
+		{
+			name: "未知の名前付き文字参照の場合にそのまま残る",
+			args: args{html: "<p>&hellip; &copy;</p>"},
+			want: "&hellip; &copy;",
+		},
+		{
+			name: "隣接するcodeタグの場合に空白で区切られた別々のコードになる",
+			args: args{html: "<code>a</code><code>b</code>"},
+			want: "`a` `b`",
+		},
+		{
+			name: "codeにバッククォートが含まれる場合により長い区切りが使われる",
+			args: args{html: "<code>use `backticks` here</code>"},
+			want: "``use `backticks` here``",
+		},
+		{
+			name: "codeの先頭と末尾がバッククォートの場合に空白で区切られる",
+			args: args{html: "<code>``x`</code>"},
+			want: "``` ``x` ```",
+		},
+		{
+			name: "空白で区切られたcodeタグの場合に空白が増えない",
+			args: args{html: "<code>a</code> <code>b</code>"},
+			want: "`a` `b`",
+		},
+		{
+			name: "重なり合う強調タグの場合に入れ子に直されたマーカーになる",
+			args: args{html: "<p><b>bold <i>both</b> italic</i></p>"},
+			want: "**bold *both*** *italic*",
+		},
+		{
+			name: "重なり合う3つの強調タグの場合に入れ子に直されたマーカーになる",
+			args: args{html: "<p><b>a <i>b <del>c</b> d</i> e</del></p>"},
+			want: "**a *b ~~c~~*** *~~d~~* ~~e~~",
+		},
+		{
+			name: "strongタグの内側に前後の空白がある場合に空白がマーカーの外に出る",
+			args: args{html: "<p>a<strong> bold </strong>b</p>"},
+			want: "a **bold** b",
+		},
+		{
+			name: "emタグの内側に先頭の空白がある場合に空白がマーカーの外に出る",
+			args: args{html: "<p>a<em> italic</em>, b</p>"},
+			want: "a *italic*, b",
+		},
+		{
+			name: "delタグの内側に末尾の空白がある場合に空白がマーカーの外に出る",
+			args: args{html: "<p><del>gone </del>kept</p>"},
+			want: "~~gone~~ kept",
+		},
+		{
+			name: "強調タグの中身が空白だけの場合にマーカーが出力されない",
+			args: args{html: "<p>a<b> </b>b</p>"},
+			want: "a b",
+		},
+		{
+			name: "ttタグの場合にバッククォートで囲まれる",
+			args: args{html: "<tt>mono</tt>"},
+			want: "`mono`",
+		},
+		{
+			name: "バッククォートを含むttタグの場合にcodeタグと同様にエスケープされる",
+			args: args{html: "<TT>a `b` c</TT>"},
+			want: "``a `b` c``",
+		},
+		{
+			name: "kbdタグの場合にバッククォートで囲まれる",
+			args: args{html: "<kbd>Enter</kbd>"},
+			want: "`Enter`",
+		},
+		{
+			name: "kbdタグの組み合わせの場合にキーごとに囲まれる",
+			args: args{html: "<kbd>Ctrl</kbd>+<kbd>C</kbd>"},
+			want: "`Ctrl`+`C`",
+		},
+		{
+			name: "ネストしたkbdタグの場合にひとつのコードにまとめられる",
+			args: args{html: "<kbd><kbd>Ctrl</kbd>+<kbd>C</kbd></kbd>"},
+			want: "`Ctrl+C`",
+		},
 		{
 			name: "preとcodeタグの場合にコードブロックに変換される",
 			args: args{html: "<pre><code>func main() {}</code></pre>"},
 			want: "```\nfunc main() {}\n```",
 		},
+		{
+			name: "コードの前後に改行がある場合にフェンスに余分な空行が入らない",
+			args: args{html: "<pre><code>\ncode\n</code></pre>"},
+			want: "```\ncode\n```",
+		},
+		{
+			name: "コード内部の空行は保持される",
+			args: args{html: "<pre>\na\n\nb\n\n</pre>"},
+			want: "```\na\n\nb\n\n```",
+		},
+		{
+			name: "コード内のインデントは圧縮されない",
+			args: args{html: "<pre><code>if x {\n\treturn\n    y\n}</code></pre>"},
+			want: "```\nif x {\n\treturn\n    y\n}\n```",
+		},
+		{
+			name: "コードブロック内のエスケープされたHTMLが保持される",
+			args: args{html: "<pre><code>&lt;b&gt;x&lt;/b&gt; &amp;&amp; *y*</code></pre>"},
+			want: "```\n<b>x</b> && *y*\n```",
+		},
+		{
+			name: "コードブロック内のハイライト用タグが除去される",
+			args: args{html: `<pre><code><span class="k">func</span> <span class="nf">main</span>()</code></pre>`},
+			want: "```\nfunc main()\n```",
+		},
+		{
+			name: "行番号付きのコードテーブルの場合に行番号を除いたコードブロックになる",
+			args: args{html: `<table class="highlighttable"><tr><td class="linenos"><div class="linenodiv"><pre>1
+2</pre></div></td><td class="code"><div class="highlight"><pre><span class="k">if</span> x &lt; 1 {
+    <span class="n">y</span>()
+}</pre></div></td></tr></table>`},
+			want: "```\nif x < 1 {\n    y()\n}\n```",
+		},
+		{
+			name: "1行ごとの行番号付きテーブルの場合にインデントを保ったコードブロックになる",
+			args: args{html: "<table><tr><td class=\"lineno\">1</td><td class=\"code\">def f():</td></tr><tr><td class=\"lineno\">2</td><td class=\"code\">    return 1</td></tr></table>"},
+			want: "```\ndef f():\n    return 1\n```",
+		},
 		// リスト
 		{
 			name: "ulとliタグの場合に箇条書きに変換される",
@@ -125,12 +395,112 @@ func TestConvert(t *testing.T) {
 			args: args{html: "<ol><li>First</li><li>Second</li></ol>"},
 			want: "1. First\n2. Second",
 		},
+		{
+			name: "liに複数のpタグがある場合に後続段落がインデントされる",
+			args: args{html: "<ul><li><p>First para</p><p>Second para</p></li><li>Next</li></ul>"},
+			want: "- First para\n\n  Second para\n- Next",
+		},
+		{
+			name: "olのliに複数のpタグがある場合に番号幅でインデントされる",
+			args: args{html: "<ol><li><p>First para</p><p>Second para</p></li></ol>"},
+			want: "1. First para\n\n   Second para",
+		},
+		{
+			name: "liにインデントされたコードがある場合にコードのインデントが保たれる",
+			args: args{html: "<ul><li>Example:<pre><code>def f():\n    return 1\n\n  x</code></pre></li></ul>"},
+			want: "- Example:\n\n  ```\n  def f():\n      return 1\n\n    x\n  ```",
+		},
+		{
+			name: "ネストしたliにインデントされたコードがある場合にコードのインデントが保たれる",
+			args: args{html: "<ul><li>a<ul><li>b<pre><code>x\n    y</code></pre></li></ul></li></ul>"},
+			want: "- a\n  - b\n\n    ```\n    x\n        y\n    ```",
+		},
+		{
+			name: "ネストしたulの場合に親項目の下にインデントされる",
+			args: args{html: "<ul><li>Parent<ul><li>Child 1</li><li>Child 2</li></ul></li><li>Next</li></ul>"},
+			want: "- Parent\n  - Child 1\n  - Child 2\n- Next",
+		},
+		{
+			name: "3階層にネストしたリストの場合に階層ごとにインデントされる",
+			args: args{html: "<ul><li>A<ol><li>B<ul><li>C</li></ul></li></ol></li></ul>"},
+			want: "- A\n  1. B\n     - C",
+		},
+		{
+			name: "ネストしたolの場合に各リストの番号が1から始まる",
+			args: args{html: "<ol><li>A<ol><li>A1</li><li>A2</li></ol></li><li>B<ol><li>B1</li></ol></li></ol>"},
+			want: "1. A\n   1. A1\n   2. A2\n2. B\n   1. B1",
+		},
+		{
+			name: "ulの直下にテキストがある場合に直前の項目に続けて出力される",
+			args: args{html: "<ul><li>A</li>loose text<li>B</li></ul>"},
+			want: "- A\n  loose text\n- B",
+		},
+		{
+			name: "ulの先頭にテキストがある場合にリストの前の行として出力される",
+			args: args{html: "<ul>Heading text<li>A</li></ul>"},
+			want: "Heading text\n\n- A",
+		},
+		{
+			name: "liのネストしたリストの後にテキストがある場合に項目に含まれる",
+			args: args{html: "<ul><li>A<ul><li>A1</li></ul>tail</li></ul>"},
+			want: "- A\n  - A1\n  tail",
+		},
+		{
+			name: "olにstart属性がある場合にその番号から始まる",
+			args: args{html: `<ol start="3"><li>C</li><li>D</li></ol>`},
+			want: "3. C\n4. D",
+		},
+		{
+			name: "olにreversed属性がある場合に項目数から1まで降順に番号が付く",
+			args: args{html: `<ol reversed><li>C</li><li>B</li><li>A</li></ol>`},
+			want: "3. C\n2. B\n1. A",
+		},
+		{
+			name: "olにreversedとstart属性がある場合にstartから降順に番号が付く",
+			args: args{html: `<ol reversed start="10"><li>J</li><li>I</li></ol>`},
+			want: "10. J\n9. I",
+		},
+		{
+			name: "liにvalue属性がある場合にその番号から番号付けが続く",
+			args: args{html: `<ol><li>a</li><li value="5">b</li><li>c</li></ol>`},
+			want: "1. a\n5. b\n6. c",
+		},
+		{
+			name: "liのvalue属性が数値でない場合に連番のままになる",
+			args: args{html: `<ol><li>a</li><li value="x">b</li></ol>`},
+			want: "1. a\n2. b",
+		},
+		{
+			name: "インデントされたソースのリストの場合に余分な空白が残らない",
+			args: args{html: "<ul>\n  <li>\n    a\n  </li>\n  <li>  b  </li>\n</ul>"},
+			want: "- a\n- b",
+		},
+		{
+			name: "インデントされたソースのネストしたリストの場合に親項目との間に空行が入らない",
+			args: args{html: "<ul>\n  <li>\n    Parent\n    <ol>\n      <li>Child 1</li>\n      <li>\n        Child 2\n        <ul>\n          <li>Grandchild</li>\n        </ul>\n      </li>\n    </ol>\n  </li>\n  <li>Next</li>\n</ul>"},
+			want: "- Parent\n  1. Child 1\n  2. Child 2\n     - Grandchild\n- Next",
+		},
+		{
+			name: "インデントされたソースのliに複数のpタグがある場合に段落区切りが1行になる",
+			args: args{html: "<ul>\n  <li>\n    <p>Para 1</p>\n\n    <p>Para 2</p>\n  </li>\n</ul>"},
+			want: "- Para 1\n\n  Para 2",
+		},
 		// 引用
 		{
 			name: "blockquoteタグの場合に引用記法に変換される",
 			args: args{html: "<blockquote>This is a quote</blockquote>"},
 			want: "> This is a quote",
 		},
+		{
+			name: "blockquote内にhrがある場合に引用内の区切り線になる",
+			args: args{html: "<blockquote><p>a</p><hr><p>b</p></blockquote>"},
+			want: "> a\n>\n> ---\n> b",
+		},
+		{
+			name: "blockquote内に複数のpがある場合に空の>行で段落が区切られる",
+			args: args{html: "<blockquote>\n<p>One</p>\n\n<p>Two\nlines</p>\n</blockquote>"},
+			want: "> One\n>\n> Two\n> lines",
+		},
 		// テーブル
 		{
 			name: "tableタグの場合にMarkdownテーブルに変換される",
@@ -142,6 +512,71 @@ func TestConvert(t *testing.T) {
 | --- | --- |
 | Cell 1 | Cell 2 |`,
 		},
+		{
+			name: "テーブルセル内のパイプがエスケープされる",
+			args: args{html: `<table><tr><th>Expr</th></tr><tr><td>a|b <code>x || y</code> <a href="/p?a|b">l</a></td></tr></table>`},
+			want: "| Expr |\n| --- |\n| a\\|b `x \\|\\| y` [l](/p?a|b) |",
+		},
+		{
+			name: "テーブルセル内の改行が空白になる",
+			args: args{html: "<table><tr><th>A</th></tr><tr><td>line1\nline2</td></tr></table>"},
+			want: "| A |\n| --- |\n| line1 line2 |",
+		},
+		{
+			name: "ネストしたtableの場合に内側がインラインテキストになり外側が崩れない",
+			args: args{html: `<table>
+		<tr><th>Name</th><th>Detail</th></tr>
+		<tr><td>A</td><td><table><tr><td>x</td><td>1</td></tr><tr><td>y</td><td>2</td></tr></table></td></tr>
+		<tr><td>B</td><td>plain</td></tr>
+	</table>`},
+			want: `| Name | Detail |
+| --- | --- |
+| A | x, 1; y, 2 |
+| B | plain |`,
+		},
+		{
+			name: "多重にネストしたtableの場合にタグが漏れない",
+			args: args{html: `<table><tr><td><table><tr><td><table><tr><td>deep</td></tr></table></td><td>mid</td></tr></table></td></tr></table>`},
+			want: "| deep, mid |\n| --- |",
+		},
+		// 折りたたみ
+		{
+			name: "detailsタグの場合にHTMLとして保持されsummaryが太字になる",
+			args: args{html: "<details><summary>More <em>info</em></summary><p>Hidden <b>text</b></p></details>"},
+			want: "<details>\n<summary><b>More info</b></summary>\n\nHidden **text**\n\n</details>",
+		},
+		{
+			name: "ソフトハイフンとゼロ幅スペースがある場合にデフォルトで除かれる",
+			args: args{html: "<p>soft\u00adware zero\u200bwidth</p>"},
+			want: "software zerowidth",
+		},
+		{
+			name: "テキストがhrefと同じリンクの場合にデフォルトでオートリンクに変換される",
+			args: args{html: `<p>See <a href="https://x.com">https://x.com</a></p>`},
+			want: "See <https://x.com>",
+		},
+		{
+			name: "セルにalign属性やtext-alignがある場合に区切り行に配置が反映される",
+			args: args{html: `<table>
+		<tr><th align="left">L</th><th style="text-align: right">R</th><th style="text-align:center">C</th><th>N</th></tr>
+		<tr><td>1</td><td>2</td><td>3</td><td>4</td></tr>
+	</table>`},
+			want: "| L | R | C | N |\n| :--- | ---: | :---: | --- |\n| 1 | 2 | 3 | 4 |",
+		},
+		{
+			name: "colgroupのcolに配置がある場合に列の既定配置になりセルの配置が優先される",
+			args: args{html: `<table>
+		<colgroup><col><col span="2" style="text-align:right"></colgroup>
+		<tr><th>A</th><th>B</th><th align="center">C</th></tr>
+		<tr><td>1</td><td>2</td><td>3</td></tr>
+	</table>`},
+			want: "| A | B | C |\n| --- | ---: | :---: |\n| 1 | 2 | 3 |",
+		},
+		{
+			name: "thとtdが混在する行の場合に元の順序が保たれる",
+			args: args{html: "<table><tr><th>H</th><th>V</th></tr><tr><td>x</td><th>y</th></tr></table>"},
+			want: "| H | V |\n| --- | --- |\n| x | y |",
+		},
 		// 水平線
 		{
 			name: "hrタグの場合に---に変換される",
@@ -159,6 +594,31 @@ func TestConvert(t *testing.T) {
 			args: args{html: "Line 1<br>Line 2"},
 			want: "Line 1  \nLine 2",
 		},
+		{
+			name: "リスト項目末尾にbrがある場合に除去される",
+			args: args{html: "<ul><li>item<br></li><li>b</li></ul>"},
+			want: "- item\n- b",
+		},
+		{
+			name: "見出し末尾にbrがある場合に除去される",
+			args: args{html: "<h2>Title<br/></h2>"},
+			want: "## Title",
+		},
+		{
+			name: "テーブルセル末尾にbrがある場合に除去される",
+			args: args{html: "<table><tr><th>a<br></th></tr><tr><td>b <br /> </td></tr></table>"},
+			want: "| a |\n| --- |\n| b |",
+		},
+		{
+			name: "blockquote内の段落末尾にbrがある場合に除去される",
+			args: args{html: "<blockquote><p>q<br></p></blockquote>"},
+			want: "> q",
+		},
+		{
+			name: "brの後に内容が続く場合に改行が維持される",
+			args: args{html: "<p>a<br>b<br></p>"},
+			want: "a  \nb",
+		},
 		// 複合
 		{
 			name: "複数要素が混在する場合に正しく変換される",
@@ -178,6 +638,11 @@ This is **bold** and *italic*.`,
 			args: args{html: "plain text"},
 			want: "plain text",
 		},
+		{
+			name: "入力にプレースホルダと同じ文字列がある場合にタグとして復元されない",
+			args: args{html: "<p>\x00LT\x00script\x00GT\x00</p>"},
+			want: "\uFFFDLT\uFFFDscript\uFFFDGT\uFFFD",
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,3 +727,1010 @@ func TestConvert_WithContentExtraction(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertReadable(t *testing.T) {
+	tests := []struct {
+		name         string
+		html         string
+		opts         Options
+		wantContains []string
+		wantExcludes []string
+	}{
+		{
+			name: "extracts article from a full document",
+			html: `<html><body>
+				<nav><a href="/">Home</a><a href="/about">About</a></nav>
+				<article><h1>Title</h1><p>Main content, with prose.</p></article>
+			</body></html>`,
+			wantContains: []string{"# Title", "Main content"},
+			wantExcludes: []string{"Home", "About"},
+		},
+		{
+			name: "extracts article from a fragment without body",
+			html: `<nav><a href="/">Home</a><a href="/about">About</a></nav>
+				<article><h1>Title</h1><p>Main content, with prose.</p></article>`,
+			wantContains: []string{"# Title", "Main content"},
+			wantExcludes: []string{"Home", "About"},
+		},
+		{
+			name:         "converts whole input when no candidate is found",
+			html:         `<h1>Title</h1><p>Text</p>`,
+			wantContains: []string{"# Title", "Text"},
+		},
+		{
+			name: "applies options to the extracted content",
+			html: `<nav><a href="/">Home</a></nav>
+				<article><h1>Title</h1><p>Main content, with prose.</p></article>`,
+			opts:         Options{HeadingStyle: Setext},
+			wantContains: []string{"Title\n=====", "Main content"},
+			wantExcludes: []string{"Home"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConvertReadableWithOptions(tt.html, tt.opts)
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("ConvertReadableWithOptions() should contain %q, got:\n%s", want, got)
+				}
+			}
+
+			for _, exclude := range tt.wantExcludes {
+				if strings.Contains(got, exclude) {
+					t.Errorf("ConvertReadableWithOptions() should NOT contain %q, got:\n%s", exclude, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+	}{
+		{name: "empty input", html: ""},
+		{name: "plain text", html: "plain text"},
+		{name: "mixed blocks", html: `<h1>Title</h1><p>Some <strong>bold</strong> text.</p><ul><li>A</li></ul><pre><code>x &lt; y</code></pre>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte(tt.html)
+			got := ConvertBytes(input)
+			if want := Convert(tt.html); string(got) != want {
+				t.Errorf("ConvertBytes() = %q, want %q", got, want)
+			}
+
+			// The result must be safe to modify without touching the input
+			for i := range got {
+				got[i] = 'x'
+			}
+			if string(input) != tt.html {
+				t.Errorf("ConvertBytes() modified its input: %q", input)
+			}
+		})
+	}
+}
+
+func TestConvert_PlainText(t *testing.T) {
+	inputs := []struct {
+		name string
+		html string
+	}{
+		{name: "empty input", html: ""},
+		{name: "single line", html: "plain text"},
+		{name: "entities", html: "fish &amp; chips&nbsp;&copy; 2024 &lt;not a tag&gt;"},
+		{name: "whitespace", html: "  a \t  b\n\n\n\nc   \n\td  "},
+		{name: "markdown-like text", html: "# Heading\n\n- item\n\n" + strings.Repeat("word ", 50)},
+	}
+	options := []struct {
+		name string
+		opts Options
+	}{
+		{name: "default"},
+		{name: "all text options", opts: Options{
+			PreserveNBSP:       true,
+			CollapseWhitespace: true,
+			GenerateTOC:        true,
+			MaxLength:          40,
+			LineEnding:         CRLF,
+			Footnotes:          true,
+		}},
+	}
+
+	for _, in := range inputs {
+		for _, o := range options {
+			t.Run(in.name+"/"+o.name, func(t *testing.T) {
+				got := ConvertWithOptions(in.html, o.opts)
+				opts := o.opts
+				want := finishMarkdown(convertTags(in.html, &opts, nil), &opts, nil)
+				if got != want {
+					t.Errorf("ConvertWithOptions() = %q, want %q as from the full pipeline", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestConvert_DetailsSummaryRendered(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     Options
+		wantBold bool
+	}{
+		{name: "SummaryBoldの場合にsummaryが太字として描画される", wantBold: true},
+		{name: "SummaryPlainの場合にsummaryが太字にならない", opts: Options{SummaryStyle: SummaryPlain}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			md := ConvertWithOptions(`<details><summary>More <em>info</em> &amp; &lt;tips&gt;</summary><p>Body</p></details>`, tt.opts)
+
+			// A renderer passes the HTML block before the first blank line
+			// through unchanged, without rendering Markdown inside it
+			block, _, _ := strings.Cut(md, "\n\n")
+			doc, err := html.Parse(strings.NewReader(block))
+			if err != nil {
+				t.Fatal(err)
+			}
+			summary := findElement(doc, "summary")
+			if summary == nil {
+				t.Fatalf("no <summary> in rendered block %q", block)
+			}
+			if got := getTextContent(summary); got != "More info & <tips>" {
+				t.Errorf("rendered summary text = %q, want %q", got, "More info & <tips>")
+			}
+			if gotBold := findElement(summary, "b") != nil; gotBold != tt.wantBold {
+				t.Errorf("rendered summary bold = %v, want %v in %q", gotBold, tt.wantBold, block)
+			}
+		})
+	}
+}
+
+func TestConvertWithOptions(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		html string
+		opts Options
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		// リスト
+		{
+			name: "LooseListsが無効の場合に項目が改行のみで区切られる",
+			args: args{html: "<ul><li>A</li><li>B</li></ul>"},
+			want: "- A\n- B",
+		},
+		{
+			name: "LooseListsが有効の場合に項目が空行で区切られる",
+			args: args{html: "<ul><li>A</li><li>B</li></ul>", opts: Options{LooseLists: true}},
+			want: "- A\n\n- B",
+		},
+		{
+			name: "LooseListsが有効の場合に番号付きリストも空行で区切られる",
+			args: args{html: "<ol><li>A</li><li>B</li></ol>", opts: Options{LooseLists: true}},
+			want: "1. A\n\n2. B",
+		},
+		{
+			name: "ContinueOrderedNumberingが有効の場合に同じ階層のolが番号を引き継ぐ",
+			args: args{
+				html: "<ol><li>A<ol><li>A1</li><li>A2</li></ol></li><li>B<ol><li>B1</li></ol></li></ol><p>break</p><ol><li>C</li></ol>",
+				opts: Options{ContinueOrderedNumbering: true},
+			},
+			want: "1. A\n   1. A1\n   2. A2\n2. B\n   3. B1\n\nbreak\n\n3. C",
+		},
+		{
+			name: "ContinueOrderedNumberingが有効でもstart属性が優先される",
+			args: args{
+				html: `<ol><li>A</li></ol><ol start="7"><li>B</li></ol><ol><li>C</li></ol>`,
+				opts: Options{ContinueOrderedNumbering: true},
+			},
+			want: "1. A\n\n7. B\n\n8. C",
+		},
+		// タスクリスト
+		{
+			name: "TaskListsが有効の場合にチェックボックス付きのliがタスクリストになる",
+			args: args{html: `<ul><li><input type="checkbox" checked disabled> Done</li><li><input type="checkbox"> Todo</li></ul>`, opts: Options{TaskLists: true}},
+			want: "- [x] Done\n- [ ] Todo",
+		},
+		{
+			name: "TaskListsが有効でリスト外のチェックボックスの場合にラベルの前に印が付く",
+			args: args{html: `<form><p><input type="checkbox" id="a" checked><label for="a">I agree</label></p><p><label><input type="checkbox" name="n"> Newsletter</label></p></form>`, opts: Options{TaskLists: true}},
+			want: "[x] I agree\n\n[ ] Newsletter",
+		},
+		// 見出し
+		{
+			name: "HeadingTransformが指定された場合に見出しテキストに適用される",
+			args: args{html: "<h1>hello world</h1><h2> sub </h2><p>body</p>", opts: Options{HeadingTransform: strings.ToUpper}},
+			want: "# HELLO WORLD\n\n## SUB\n\nbody",
+		},
+		{
+			name: "HeadingOffsetが1の場合に見出しレベルが1段下がる",
+			args: args{html: "<h1>A</h1><h2>B</h2><h3>C</h3>", opts: Options{HeadingOffset: 1}},
+			want: "## A\n\n### B\n\n#### C",
+		},
+		{
+			name: "HeadingOffsetでh6を超える場合にh6に丸められる",
+			args: args{html: "<h4>A</h4><h5>B</h5><h6>C</h6>", opts: Options{HeadingOffset: 2}},
+			want: "###### A\n\n###### B\n\n###### C",
+		},
+		{
+			name: "HeadingOffsetが負の場合にh1で止まる",
+			args: args{html: "<h1>A</h1><h3>B</h3>", opts: Options{HeadingOffset: -1}},
+			want: "# A\n\n## B",
+		},
+		{
+			name: "StripHeadingNumbersが有効の場合に見出しの先頭の番号が除去される",
+			args: args{html: "<h2>1. Introduction</h2><h3>2) Setup</h3><h3>3.1. Details</h3>", opts: Options{StripHeadingNumbers: true}},
+			want: "## Introduction\n\n### Setup\n\n### Details",
+		},
+		{
+			name: "StripHeadingNumbersが有効でも年や番号のみの見出しは保持される",
+			args: args{html: "<h2>2024. A Retrospective</h2><h2>3.14 is pi</h2><h2>42.</h2>", opts: Options{StripHeadingNumbers: true}},
+			want: "## 2024. A Retrospective\n\n## 3.14 is pi\n\n## 42.",
+		},
+		{
+			name: "StripHeadingNumbersが無効の場合に見出しの番号が保持される",
+			args: args{html: "<h2>1. Introduction</h2>"},
+			want: "## 1. Introduction",
+		},
+		{
+			name: "NormalizeHeadingBaseが有効で最小の見出しがh3の場合にh1から始まる",
+			args: args{html: "<h3>Top</h3><p>a</p><h4>Sub</h4><h3>Next</h3>", opts: Options{NormalizeHeadingBase: true}},
+			want: "# Top\n\na\n\n## Sub\n\n# Next",
+		},
+		{
+			name: "NormalizeHeadingBaseとHeadingOffsetを併用した場合に正規化後にずらされる",
+			args: args{html: "<h3>Top</h3><h4>Sub</h4>", opts: Options{NormalizeHeadingBase: true, HeadingOffset: 1}},
+			want: "## Top\n\n### Sub",
+		},
+		// 許可タグ
+		{
+			name: "AllowedTagsが指定された場合に許可タグのみ変換される",
+			args: args{
+				html: `<h1>Title</h1><p>See <a href="https://example.com">link</a> and <img src="x.png"> <b>bold</b></p>`,
+				opts: Options{AllowedTags: []string{"h1", "P", "a"}},
+			},
+			want: "# Title\n\nSee [link](https://example.com) and bold",
+		},
+		{
+			name: "AllowedTagsに含まれないテーブルはテキストのみ残る",
+			args: args{
+				html: "<table><tr><td>cell</td></tr></table><p>text</p>",
+				opts: Options{AllowedTags: []string{"p"}},
+			},
+			want: "cell\n\ntext",
+		},
+		// 空白
+		{
+			name: "PreserveNBSPが無効の場合にnbspが通常スペースになる",
+			args: args{html: "<p>10&nbsp;kg</p>"},
+			want: "10 kg",
+		},
+		{
+			name: "PreserveNBSPが有効の場合に数値と単位の間のnbspが保持される",
+			args: args{html: "<p>10&nbsp;kg</p>", opts: Options{PreserveNBSP: true}},
+			want: "10\u00a0kg",
+		},
+		{
+			name: "PreserveNBSPが有効の場合に連続するnbspが圧縮されない",
+			args: args{html: "<p>a&nbsp;&nbsp; b</p>", opts: Options{PreserveNBSP: true}},
+			want: "a\u00a0\u00a0 b",
+		},
+		{
+			name: "CollapseWhitespaceが無効の場合に変換で生じた連続空白が残る",
+			args: args{html: "<p>a&nbsp;&nbsp;b</p>"},
+			want: "a  b",
+		},
+		{
+			name: "CollapseWhitespaceが有効の場合に本文の連続空白が1つになる",
+			args: args{html: "<p>a&nbsp;&nbsp;b&nbsp; &nbsp;c</p>", opts: Options{CollapseWhitespace: true}},
+			want: "a b c",
+		},
+		{
+			name: "CollapseWhitespaceが有効でもコードと改行の末尾空白は保持される",
+			args: args{html: "<p>x&nbsp;&nbsp;<code>a&nbsp;&nbsp;b</code><br>y</p><pre>if  x {\n\treturn  y\n}</pre>", opts: Options{CollapseWhitespace: true}},
+			want: "x `a  b`  \ny\n\n```\nif  x {\n\treturn  y\n}\n```",
+		},
+		{
+			name: "CollapseWhitespaceが有効でもインデント形式のコードブロックは保持される",
+			args: args{html: "<p>a&nbsp;&nbsp;b</p><pre>x  =  1</pre>", opts: Options{CollapseWhitespace: true, CodeBlockStyle: Indented}},
+			want: "a b\n\n    x  =  1",
+		},
+		// コード
+		{
+			name: "TabWidthが指定された場合にコードの先頭タブが空白に展開される",
+			args: args{html: "<pre><code>func f() {\n\tif x {\n\t\treturn\ta\n\t}\n}</code></pre>", opts: Options{TabWidth: 4}},
+			want: "```\nfunc f() {\n    if x {\n        return\ta\n    }\n}\n```",
+		},
+		{
+			name: "CodeBlockStyleがIndentedの場合に各行が4スペースでインデントされる",
+			args: args{html: "<pre><code>a := 1\n\nif a {\n\treturn\n}</code></pre><p>after</p>", opts: Options{CodeBlockStyle: Indented}},
+			want: "    a := 1\n\n    if a {\n    \treturn\n    }\n\nafter",
+		},
+		{
+			name: "CodeBlockStyleがIndentedの場合に段落の後のコードが空行で区切られる",
+			args: args{html: "<p>before</p><pre>x &lt; y</pre>", opts: Options{CodeBlockStyle: Indented}},
+			want: "before\n\n    x < y",
+		},
+		{
+			name: "TabWidthが指定されても本文のタブは影響を受けない",
+			args: args{html: "<p>a\tb</p>", opts: Options{TabWidth: 4}},
+			want: "a b",
+		},
+		{
+			name: "DedentCodeが有効の場合にコードブロックの共通インデントが除去される",
+			args: args{html: "<pre><code>        func f() {\n\n            return\n        }</code></pre>", opts: Options{DedentCode: true}},
+			want: "```\nfunc f() {\n\n    return\n}\n```",
+		},
+		{
+			name: "DedentCodeが無効の場合にコードブロックのインデントが保持される",
+			args: args{html: "<pre><code>    x\n      y</code></pre>"},
+			want: "```\n    x\n      y\n```",
+		},
+		// テーブル
+		{
+			name: "SingleColumnTableAsListが有効で1列のテーブルの場合にリストになる",
+			args: args{
+				html: "<table><tr><th>Fruits</th></tr><tr><td>Apple</td></tr><tr><td>Banana</td></tr></table>",
+				opts: Options{SingleColumnTableAsList: true},
+			},
+			want: "- Fruits\n- Apple\n- Banana",
+		},
+		{
+			name: "SingleColumnHeaderLevelが指定された場合にヘッダが見出しになる",
+			args: args{
+				html: "<table><tr><th>Fruits</th></tr><tr><td>Apple</td></tr><tr><td>Banana</td></tr></table>",
+				opts: Options{SingleColumnTableAsList: true, SingleColumnHeaderLevel: 3},
+			},
+			want: "### Fruits\n\n- Apple\n- Banana",
+		},
+		{
+			name: "SingleColumnHeaderLevelが指定されても先頭行がtdの場合は項目になる",
+			args: args{
+				html: "<table><tr><td>Apple</td></tr><tr><td>Banana</td></tr></table>",
+				opts: Options{SingleColumnTableAsList: true, SingleColumnHeaderLevel: 3},
+			},
+			want: "- Apple\n- Banana",
+		},
+		{
+			name: "SingleColumnTableAsListが有効でも複数列のテーブルは変換されない",
+			args: args{
+				html: "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>",
+				opts: Options{SingleColumnTableAsList: true},
+			},
+			want: "| A | B |\n| --- | --- |\n| 1 | 2 |",
+		},
+		// テーブル内の改行
+		{
+			name: "TableCellBreakが既定の場合にセル内のbrがHTMLのbrになる",
+			args: args{html: "<table><tr><th>A</th><th>B</th></tr><tr><td>x<br>y</td><td>z</td></tr></table>"},
+			want: "| A | B |\n| --- | --- |\n| x<br>y | z |",
+		},
+		{
+			name: "TableCellBreakがSpaceBreakの場合にセル内のbrが空白になる",
+			args: args{html: "<table><tr><th>A</th><th>B</th></tr><tr><td>x <br/> y</td><td>z</td></tr></table>", opts: Options{TableCellBreak: SpaceBreak}},
+			want: "| A | B |\n| --- | --- |\n| x y | z |",
+		},
+		{
+			name: "TableCellBreakがStripBreakの場合にセル内のbrが除去される",
+			args: args{html: "<table><tr><th>A</th><th>B</th></tr><tr><td>x<br>y</td><td>z</td></tr></table>", opts: Options{TableCellBreak: StripBreak}},
+			want: "| A | B |\n| --- | --- |\n| xy | z |",
+		},
+		// ルビ
+		{
+			name: "RubyStyleがRubyBaseOnlyの場合に読みが除去される",
+			args: args{html: "<ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>", opts: Options{RubyStyle: RubyBaseOnly}},
+			want: "漢字",
+		},
+		{
+			name: "RubyStyleがRubyHTMLの場合にrubyタグが保持される",
+			args: args{html: "<ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>", opts: Options{RubyStyle: RubyHTML}},
+			want: "<ruby>漢字<rt>かんじ</rt></ruby>",
+		},
+		// コメント
+		{
+			name: "KeepCommentsがStripの場合に>を含むコメントも除去される",
+			args: args{html: "<p>a<!-- 1 > 0 -->b</p>"},
+			want: "ab",
+		},
+		{
+			name: "KeepCommentsがHTMLPassthroughの場合にコメントがそのまま残る",
+			args: args{html: "<p>a<!-- note <b>x</b> --></p>", opts: Options{KeepComments: HTMLPassthrough}},
+			want: "a<!-- note <b>x</b> -->",
+		},
+		{
+			name: "KeepCommentsがMarkdownLinkの場合にリンク参照形式のコメントになる",
+			args: args{html: "<p>a</p><!-- see\n (issue 1) --><p>b</p>", opts: Options{KeepComments: MarkdownLink}},
+			want: "a\n\n[//]: # (see \\(issue 1\\))\n\nb",
+		},
+		{
+			name: "KeepCommentsがMarkdownLinkで空のコメントの場合に何も出力されない",
+			args: args{html: "<p>a</p><!-- --><p>b</p>", opts: Options{KeepComments: MarkdownLink}},
+			want: "a\n\nb",
+		},
+		// 折りたたみ
+		{
+			name: "SummaryStyleがSummaryPlainの場合にsummaryが太字にならない",
+			args: args{html: "<details><summary>More</summary>Body</details>", opts: Options{SummaryStyle: SummaryPlain}},
+			want: "<details>\n<summary>More</summary>\n\nBody\n\n</details>",
+		},
+		// URL
+		{
+			name: "StripTrackingParamsが有効の場合にリンクと画像の追跡パラメータが除去される",
+			args: args{
+				html: `<a href="https://example.com/a?id=1&amp;utm_source=x&fbclid=y">A</a> <img src="/i.png?gclid=z" alt="i">`,
+				opts: Options{StripTrackingParams: true},
+			},
+			want: "[A](https://example.com/a?id=1) ![i](/i.png)",
+		},
+		{
+			name: "StripTrackingParamsでTrackingParamsを指定した場合にその接頭辞のみ除去される",
+			args: args{
+				html: `<a href="https://example.com/?ref=x&utm_source=y&b=2">A</a>`,
+				opts: Options{StripTrackingParams: true, TrackingParams: []string{"ref"}},
+			},
+			want: "[A](https://example.com/?utm_source=y&b=2)",
+		},
+		{
+			name: "StripTrackingParamsが無効の場合にURLはそのまま出力される",
+			args: args{html: `<a href="https://example.com/?utm_source=x">A</a>`},
+			want: "[A](https://example.com/?utm_source=x)",
+		},
+		// 参照リンク
+		{
+			name: "ReferenceLinksが有効の場合に番号付きの参照リンクと定義が出力される",
+			args: args{html: `<p><a href="https://a.test">A</a>, <a href="https://b.test">B</a>, <a href="https://a.test">again</a></p>`, opts: Options{ReferenceLinks: true}},
+			want: "[A][1], [B][2], [again][1]\n\n[1]: https://a.test\n[2]: https://b.test",
+		},
+		{
+			name: "ReferenceLabelStyleがTextLabelsの場合にリンクテキストのスラッグがラベルになり衝突は連番になる",
+			args: args{html: `<p><a href="https://a.test">Example <b>Site</b></a> <a href="https://b.test">example site!</a> <a href="https://a.test">Example Site</a> <a href="https://c.test"><img src="i.png"></a></p>`, opts: Options{ReferenceLinks: true, ReferenceLabelStyle: TextLabels}},
+			want: "[Example **Site**][example-site] [example site!][example-site-2] [Example Site][example-site] [![](i.png)][link]\n\n[example-site]: https://a.test\n[example-site-2]: https://b.test\n[link]: https://c.test",
+		},
+		// 画像
+		{
+			name: "ImagesAsHTMLが有効の場合にimgタグとして出力されloadingとdecodingが保持される",
+			args: args{
+				html: `<p><img class="x" src="a.png" loading="lazy" alt="A" decoding="async" width="10"></p>`,
+				opts: Options{ImagesAsHTML: true},
+			},
+			want: `<img src="a.png" alt="A" loading="lazy" decoding="async">`,
+		},
+		{
+			name: "ImagesAsHTMLでImageKeepAttrsを指定した場合にその属性のみ保持される",
+			args: args{
+				html: `<img src="a.png" loading="lazy" width="10" height="20">`,
+				opts: Options{ImagesAsHTML: true, ImageKeepAttrs: []string{"width", "height"}},
+			},
+			want: `<img src="a.png" width="10" height="20">`,
+		},
+		{
+			name: "DataURIImagesが既定の場合にdata URIの画像がそのまま出力される",
+			args: args{html: `<img src="data:image/png;base64,iVBORw0KGgo=" alt="dot">`},
+			want: "![dot](data:image/png;base64,iVBORw0KGgo=)",
+		},
+		{
+			name: "DataURIImagesがDataURIStripの場合にdata URIの画像が除去される",
+			args: args{html: `<p>Logo:<img src="data:image/png;base64,iVBORw0KGgo=" alt="dot"></p><p><img src="/x.png" alt="x"></p>`, opts: Options{DataURIImages: DataURIStrip}},
+			want: "Logo:\n\n![x](/x.png)",
+		},
+		{
+			name: "DataURIImagesがDataURIPlaceholderの場合にdata URIがプレースホルダになる",
+			args: args{html: `<img src=" DATA:image/png;base64,iVBORw0KGgo=" alt="dot">`, opts: Options{DataURIImages: DataURIPlaceholder}},
+			want: "![dot](data-image)",
+		},
+		// フォーム
+		{
+			name: "フォームの場合にラベルとbuttonとoutputのテキストが残りselectの選択肢が除かれる",
+			args: args{html: `<form><p><label for="q">Search</label> <input id="q" type="text"> <button type="submit">Go</button></p><p><label>Size <select name="s"><option>S</option><option selected>M</option></select></label></p><p>Total: <output>42</output></p><datalist id="d"><option value="x"></datalist></form>`},
+			want: "Search Go\n\nSize\n\nTotal: 42",
+		},
+		{
+			name: "隣接するフォーム部品の場合にテキストが空白で区切られる",
+			args: args{html: `<p><button>Go</button><output>42</output></p><p><label>Name</label><input type="text"><button>Save</button><button>Cancel</button>done</p><p>Total<output>7</output>, <b>ok</b></p>`},
+			want: "Go 42\n\nName Save Cancel done\n\nTotal 7, **ok**",
+		},
+		// 図
+		{
+			name: "amp-imgの場合に通常の画像になりnoscriptの代替画像は出力されない",
+			args: args{html: `<p><amp-img src="photo.jpg" alt="Photo" width="800" height="600" layout="responsive"><noscript><img src="photo.jpg" alt="Photo"></noscript></amp-img></p>`},
+			want: "![Photo](photo.jpg)",
+		},
+		{
+			name: "閉じタグのないamp-animの場合に通常の画像になる",
+			args: args{html: `<p>Look: <AMP-ANIM src="a.gif" alt="Anim"></p>`},
+			want: "Look: ![Anim](a.gif)",
+		},
+		{
+			name: "amp-videoの場合にvideoと同様に代替テキストが残る",
+			args: args{html: `<amp-video src="v.mp4" width="640" height="360"><div fallback>Your browser does not support video.</div></amp-video>`},
+			want: "Your browser does not support video.",
+		},
+		{
+			name: "pictureに複数のsourceとimgがある場合にimgだけが画像になる",
+			args: args{html: `<picture><source media="(min-width: 800px)" srcset="large.webp 1x, large@2x.webp 2x"><source media="(min-width: 400px)" srcset="medium.webp"><img src="fallback.jpg" alt="Photo"></picture>`},
+			want: "![Photo](fallback.jpg)",
+		},
+		{
+			name: "pictureにimgがない場合に最初のsourceの画像になる",
+			args: args{html: `<picture><source srcset="a.webp 1x, b.webp 2x"><source srcset="c.webp"></picture>`},
+			want: "![](a.webp)",
+		},
+		{
+			name: "figcaptionが画像の前にある場合に画像の下に出力される",
+			args: args{html: `<figure><figcaption>Caption first</figcaption><img src="a.png" alt="A"></figure>`},
+			want: "![A](a.png)\n\n*Caption first*",
+		},
+		{
+			name: "figcaptionが画像の後にある場合に画像の下に出力される",
+			args: args{html: `<figure><img src="b.png" alt="B"><figcaption>Caption last</figcaption></figure>`},
+			want: "![B](b.png)\n\n*Caption last*",
+		},
+		{
+			name: "FigureCaptionがCaptionAboveの場合にキャプションが画像の上に出力される",
+			args: args{html: `<figure><img src="b.png" alt="B"><figcaption>Caption last</figcaption></figure>`, opts: Options{FigureCaption: CaptionAbove}},
+			want: "*Caption last*\n\n![B](b.png)",
+		},
+		// 脚注
+		{
+			name: "Footnotesが有効の場合に脚注記法に変換される",
+			args: args{
+				html: `<p>Claim<sup><a href="#fn1" id="ref1">1</a></sup> and more<sup><a href="#fn2" id="ref2">[2]</a></sup>.</p>
+<ol class="footnotes">
+<li id="fn1">First <em>source</em>. <a href="#ref1">↩</a></li>
+<li id="fn2"><p>Second source.</p> <a href="#ref2">↩</a></li>
+</ol>`,
+				opts: Options{Footnotes: true},
+			},
+			want: "Claim[^1] and more[^2].\n\n[^1]: First *source*.\n[^2]: Second source.",
+		},
+		{
+			name: "Footnotesが有効でdivの脚注セクションの場合も変換される",
+			args: args{
+				html: `<p>Text<sup><a href="#note-a">a</a></sup></p><div class="footnotes"><hr><ol><li id="note-a">Note <a href="#ref-a">&#8617;</a></li></ol></div>`,
+				opts: Options{Footnotes: true},
+			},
+			want: "Text[^a]\n\n[^a]: Note",
+		},
+		{
+			name: "Footnotesが無効の場合に脚注は変換されない",
+			args: args{html: `<p>Claim<sup><a href="#fn1">1</a></sup></p>`},
+			want: "Claim[1](#fn1)",
+		},
+		// 目次
+		{
+			name: "GenerateTOCが有効の場合に見出しの入れ子リストが先頭に追加される",
+			args: args{html: "<h1>Guide</h1><h2>Install</h2><h3>On macOS</h3><h2>Usage</h2>", opts: Options{GenerateTOC: true}},
+			want: "- [Guide](#guide)\n  - [Install](#install)\n    - [On macOS](#on-macos)\n  - [Usage](#usage)\n\n# Guide\n\n## Install\n\n### On macOS\n\n## Usage",
+		},
+		{
+			name: "GenerateTOCが有効でTOCコメントがある場合にその位置に挿入される",
+			args: args{html: "<p>Intro</p><!-- TOC --><h2>A</h2>", opts: Options{GenerateTOC: true}},
+			want: "Intro\n\n- [A](#a)\n\n## A",
+		},
+		{
+			name: "GenerateTOCが有効の場合にアンカーが句読点除去と連番で一意になる",
+			args: args{html: "<h2>Hello, <em>World</em>!</h2><h2>Hello World</h2><h2>Q&amp;A</h2>", opts: Options{GenerateTOC: true}},
+			want: "- [Hello, World!](#hello-world)\n- [Hello World](#hello-world-1)\n- [Q&A](#qa)\n\n## Hello, *World*!\n\n## Hello World\n\n## Q&A",
+		},
+		{
+			name: "GenerateTOCが有効でもコードブロック内の#は見出しにならない",
+			args: args{html: "<pre><code># comment</code></pre><h2>Real</h2>", opts: Options{GenerateTOC: true, HeadingStyle: Setext}},
+			want: "- [Real](#real)\n\n```\n# comment\n```\n\nReal\n----",
+		},
+		{
+			name: "GenerateTOCが有効で見出しがない場合にTOCコメントが除去される",
+			args: args{html: "<p>a</p><!-- TOC --><p>b</p>", opts: Options{GenerateTOC: true}},
+			want: "a\n\nb",
+		},
+		// 見出しのアンカー
+		{
+			name: "HeadingAnchorsが未指定の場合に見出しのidが出力されない",
+			args: args{html: `<h2 id="install">Install</h2>`},
+			want: "## Install",
+		},
+		{
+			name: "HeadingAnchorsがAnchorAttrの場合に見出しの後に{#id}が付く",
+			args: args{html: `<h2 id="install">Install</h2>`, opts: Options{HeadingAnchors: AnchorAttr}},
+			want: "## Install {#install}",
+		},
+		{
+			name: "HeadingAnchorsがAnchorAttrでSetextの場合に下線の前の行に{#id}が付く",
+			args: args{html: `<h1 id="top">Top</h1>`, opts: Options{HeadingAnchors: AnchorAttr, HeadingStyle: Setext}},
+			want: "Top {#top}\n===",
+		},
+		{
+			name: "HeadingAnchorsがAnchorHTMLの場合に見出しの前にaタグのアンカーが出力される",
+			args: args{html: `<p>Intro</p><h2 id="install">Install</h2>`, opts: Options{HeadingAnchors: AnchorHTML}},
+			want: "Intro\n\n<a id=\"install\"></a>\n## Install",
+		},
+		{
+			name: "GenerateTOCとAnchorAttrが有効の場合に目次が{#id}を除いた見出しとidへのリンクになる",
+			args: args{html: `<h1 id="intro">Intro</h1><h2>Setup</h2><h2 id="use-it">Usage</h2>`, opts: Options{GenerateTOC: true, HeadingAnchors: AnchorAttr}},
+			want: "- [Intro](#intro)\n  - [Setup](#setup)\n  - [Usage](#use-it)\n\n# Intro {#intro}\n\n## Setup\n\n## Usage {#use-it}",
+		},
+		{
+			name: "GenerateTOCとAnchorHTMLが有効の場合に目次がaタグのidへのリンクになる",
+			args: args{html: `<h1 id="intro">Intro</h1><h2 id="use-it">Usage</h2>`, opts: Options{GenerateTOC: true, HeadingAnchors: AnchorHTML}},
+			want: "- [Intro](#intro)\n  - [Usage](#use-it)\n\n<a id=\"intro\"></a>\n# Intro\n\n<a id=\"use-it\"></a>\n## Usage",
+		},
+		{
+			name: "HeadingAnchorsが有効でidがない場合に見出しがそのまま出力される",
+			args: args{html: `<h2 class="x">Install</h2>`, opts: Options{HeadingAnchors: AnchorHTML}},
+			want: "## Install",
+		},
+		// 複雑なテーブル
+		{
+			name: "ComplexTableAsHTMLが有効でrowspanがある場合にテーブルがHTMLのまま出力される",
+			args: args{html: "<p>Before</p><table class=\"t\" style=\"x\">\n  <tr><th>H1</th><th>H2</th></tr>\n\n  <tr><td rowspan=\"2\" onclick=\"x()\">A &amp; <span>B</span></td><td><a href=\"/c\">C</a></td></tr>\n  <tr><td>&lt;D&gt;</td></tr>\n</table>", opts: Options{ComplexTableAsHTML: true}},
+			want: "Before\n\n<table>\n<tr><th>H1</th><th>H2</th></tr>\n<tr><td rowspan=\"2\">A &amp; B</td><td><a href=\"/c\">C</a></td></tr>\n<tr><td>&lt;D&gt;</td></tr>\n</table>",
+		},
+		{
+			name: "ComplexTableAsHTMLが有効でセルにリストがある場合にテーブルがHTMLのまま出力される",
+			args: args{html: `<table><tr><td><ul><li>a</li><li>b</li></ul></td></tr></table>`, opts: Options{ComplexTableAsHTML: true}},
+			want: "<table><tr><td><ul><li>a</li><li>b</li></ul></td></tr></table>",
+		},
+		{
+			name: "ComplexTableAsHTMLが有効でtheadに複数行がある場合にテーブルがHTMLのまま出力される",
+			args: args{html: `<table><thead><tr><th colspan="2">G</th></tr><tr><th>a</th><th>b</th></tr></thead><tbody><tr><td>1</td><td>2</td></tr></tbody></table>`, opts: Options{ComplexTableAsHTML: true}},
+			want: `<table><thead><tr><th colspan="2">G</th></tr><tr><th>a</th><th>b</th></tr></thead><tbody><tr><td>1</td><td>2</td></tr></tbody></table>`,
+		},
+		{
+			name: "ComplexTableAsHTMLが有効で危険なスキームのhrefの場合にエンコードされていてもhrefが除かれる",
+			args: args{html: `<table><tr><td rowspan="2"><a href="&#106;avascript:alert(1)">a</a> <a href="java&#x09;script:x()">b</a> <a href=" javascript:x()">c</a> <a href="data:text/html,x">d</a></td></tr><tr><td><a href="https://e.example/?a=1&amp;b=2">e</a> <a href="mailto:f@example.com">f</a> <a href="/g:h">g</a></td></tr></table>`, opts: Options{ComplexTableAsHTML: true}},
+			want: `<table><tr><td rowspan="2"><a>a</a> <a>b</a> <a>c</a> <a>d</a></td></tr><tr><td><a href="https://e.example/?a=1&amp;b=2">e</a> <a href="mailto:f@example.com">f</a> <a href="/g:h">g</a></td></tr></table>`,
+		},
+		{
+			name: "ComplexTableAsHTMLが有効で単純なテーブルの場合にMarkdownのテーブルに変換される",
+			args: args{html: `<table><tr><th>A</th></tr><tr><td rowspan="1">1</td></tr></table>`, opts: Options{ComplexTableAsHTML: true}},
+			want: "| A |\n| --- |\n| 1 |",
+		},
+		{
+			name: "ComplexTableAsHTMLが無効の場合にrowspanのあるテーブルもMarkdownに変換される",
+			args: args{html: `<table><tr><th>A</th><th>B</th></tr><tr><td rowspan="2">1</td><td>2</td></tr></table>`},
+			want: "| A | B |\n| --- | --- |\n| 1 | 2 |",
+		},
+		// white-spaceで整形されたテキスト
+		{
+			name: "white-space:preのdivの場合にインデントを保ったコードブロックになる",
+			args: args{html: "<p>Output:</p><div style=\"font-family: monospace; white-space: pre\">def f():\n    return 1</div><p>Done</p>"},
+			want: "Output:\n\n```\ndef f():\n    return 1\n```\n\nDone",
+		},
+		{
+			name: "white-space:pre-wrapのdivの場合にbrが改行になりネストしたdivも含まれる",
+			args: args{html: "<div style=\"WHITE-SPACE:pre-wrap\">a  b<br><div>  c</div></div>", opts: Options{CodeBlockStyle: Indented}},
+			want: "    a  b\n      c",
+		},
+		{
+			name: "white-space:normalのdivの場合に空白が正規化される",
+			args: args{html: "<div style=\"white-space: normal\">a   b</div>"},
+			want: "a b",
+		},
+		// 区切りのdiv
+		{
+			name: "SeparatorClassesに一致するclassのdivの場合に水平線になる",
+			args: args{html: `<p>Before</p><div class="separator" style="clear: both; text-align: center;"></div><p>After</p>`, opts: Options{SeparatorClasses: []string{"separator"}}},
+			want: "Before\n\n---\n\nAfter",
+		},
+		{
+			name: "SeparatorClassesに一致するdivに画像がある場合に水平線の後に画像が残る",
+			args: args{html: `<p>Before</p><div class="post-body Separator"><a href="big.jpg"><img src="small.jpg" alt="pic"></a></div><p>After</p>`, opts: Options{SeparatorClasses: []string{"separator"}}},
+			want: "Before\n\n---\n\n[![pic](small.jpg)](big.jpg)\n\nAfter",
+		},
+		{
+			name: "SeparatorClassesが未指定の場合にseparatorのdivが水平線にならない",
+			args: args{html: `<p>Before</p><div class="separator"></div><p>After</p>`},
+			want: "Before\n\nAfter",
+		},
+		// 自動リンク
+		{
+			name: "Autolinkが有効の場合にテキスト中のURLが自動リンクになる",
+			args: args{html: `<p>Visit https://example.com today, or http://example.org/a?b=1&amp;c=2.</p>`, opts: Options{Autolink: true}},
+			want: "Visit <https://example.com> today, or <http://example.org/a?b=1&c=2>.",
+		},
+		{
+			name: "Autolinkが有効の場合に括弧で囲まれたURLの閉じ括弧が含まれない",
+			args: args{html: `<p>(see https://en.wikipedia.org/wiki/Go_(programming_language))</p>`, opts: Options{Autolink: true}},
+			want: "(see <https://en.wikipedia.org/wiki/Go_(programming_language)>)",
+		},
+		{
+			name: "Autolinkが有効の場合にリンクやコードや属性の中のURLは変更されない",
+			args: args{html: `<p><a href="https://a.example">https://a.example</a> <code>curl https://b.example</code> <img src="https://c.example/i.png" alt="i"></p><pre>https://d.example</pre>`, opts: Options{Autolink: true}},
+			want: "[https://a.example](https://a.example) `curl https://b.example` ![i](https://c.example/i.png)\n\n```\nhttps://d.example\n```",
+		},
+		{
+			name: "Autolinkが有効でテキストがhrefと同じリンクの場合にオートリンクが二重にならない",
+			args: args{html: `<p><a href="https://a.example">https://a.example</a> and https://b.example</p>`, opts: Options{Autolink: true, CollapseSelfLinks: true}},
+			want: "<https://a.example> and <https://b.example>",
+		},
+		{
+			name: "Autolinkが有効の場合にテキストで書かれたMarkdownリンクや自動リンクは変更されない",
+			args: args{html: `<p>[x](https://a.example) &lt;https://b.example&gt;</p>`, opts: Options{Autolink: true}},
+			want: "[x](https://a.example) <https://b.example>",
+		},
+		{
+			name: "Autolinkが有効の場合にタグのないテキストのURLもオートリンクに変換される",
+			args: args{html: `Visit https://example.com today`, opts: Options{Autolink: true}},
+			want: "Visit <https://example.com> today",
+		},
+		{
+			name: "Autolinkが無効の場合にURLがそのまま出力される",
+			args: args{html: `<p>Visit https://example.com today</p>`},
+			want: "Visit https://example.com today",
+		},
+		// 注意書き
+		{
+			name: "Admonitionsが有効でnote classのdivの場合に[!NOTE]のアラートになる",
+			args: args{html: `<div class="note"><p>Be careful.</p><p>Really.</p></div>`, opts: Options{Admonitions: true}},
+			want: "> [!NOTE]\n> Be careful.\n>\n> Really.",
+		},
+		{
+			name: "Admonitionsが有効でtip classのasideの場合に[!TIP]のアラートになる",
+			args: args{html: `<aside class="tip"><p>Be careful.</p><p>Really.</p></aside>`, opts: Options{Admonitions: true}},
+			want: "> [!TIP]\n> Be careful.\n>\n> Really.",
+		},
+		{
+			name: "Admonitionsが有効でimportant classのsectionの場合に[!IMPORTANT]のアラートになる",
+			args: args{html: `<section class="important"><p>Be careful.</p><p>Really.</p></section>`, opts: Options{Admonitions: true}},
+			want: "> [!IMPORTANT]\n> Be careful.\n>\n> Really.",
+		},
+		{
+			name: "Admonitionsが有効でwarning classのasideの場合に[!WARNING]のアラートになる",
+			args: args{html: `<aside class="warning"><p>Be careful.</p><p>Really.</p></aside>`, opts: Options{Admonitions: true}},
+			want: "> [!WARNING]\n> Be careful.\n>\n> Really.",
+		},
+		{
+			name: "Admonitionsが有効でcaution classのdivの場合に[!CAUTION]のアラートになる",
+			args: args{html: `<div class="caution"><p>Be careful.</p><p>Really.</p></div>`, opts: Options{Admonitions: true}},
+			want: "> [!CAUTION]\n> Be careful.\n>\n> Really.",
+		},
+		{
+			name: "Admonitionsが有効でadmonition-titleがある場合にタイトルが除かれる",
+			args: args{html: `<div class="admonition Warning"><p class="admonition-title">Warning</p><p>Hot <strong>surface</strong>.</p></div>`, opts: Options{Admonitions: true}},
+			want: "> [!WARNING]\n> Hot **surface**.",
+		},
+		{
+			name: "AdmonitionClassesを指定した場合にそのclassが指定したアラートになる",
+			args: args{html: `<div class="callout danger">Do not <div>touch</div></div><div class="note">Plain</div>`, opts: Options{Admonitions: true, AdmonitionClasses: map[string]string{"danger": "caution"}}},
+			want: "> [!CAUTION]\n> Do not touch\n\nPlain",
+		},
+		{
+			name: "Admonitionsが無効の場合にnote classのdivがそのまま本文になる",
+			args: args{html: `<div class="note"><p>Be careful.</p></div>`},
+			want: "Be careful.",
+		},
+		// 不可視文字
+		{
+			name: "StripInvisibleCharsが有効でソフトハイフンとゼロ幅スペースとBOMがある場合にテキストから除かれる",
+			args: args{html: "\ufeff<p>Incom\u00adpre&shy;hen&#173;sible\u200bword&#x200B;s\u2060</p>", opts: Options{StripInvisibleChars: true}},
+			want: "Incomprehensiblewords",
+		},
+		{
+			name: "StripInvisibleCharsが有効でコードの中の不可視文字の場合に除かれない",
+			args: args{html: "<p>a\u00adb <code>c\u200bd</code></p><pre><code>e\u00adf</code></pre>", opts: Options{StripInvisibleChars: true}},
+			want: "ab `c\u200bd`\n\n```\ne\u00adf\n```",
+		},
+		{
+			name: "ゼロ幅接合子の場合に絵文字のために残される",
+			args: args{html: "<p>\U0001F469\u200d\U0001F4BB</p>", opts: Options{StripInvisibleChars: true}},
+			want: "\U0001F469\u200d\U0001F4BB",
+		},
+		{
+			name: "エスケープされた&shy;の場合にテキストとして残される",
+			args: args{html: "<p>a &amp;shy; b</p>", opts: Options{StripInvisibleChars: true}},
+			want: "a &shy; b",
+		},
+		{
+			name: "タグのないテキストでエスケープされた&shy;の場合にテキストとして残される",
+			args: args{html: "a &amp;shy; b", opts: Options{StripInvisibleChars: true}},
+			want: "a &shy; b",
+		},
+		{
+			name: "StripInvisibleCharsが無効の場合に不可視文字が残される",
+			args: args{html: "<p>a\u00adb\u200bc</p>"},
+			want: "a\u00adb\u200bc",
+		},
+		// URLをテキストにするリンク
+		{
+			name: "CollapseSelfLinksが有効でリンクのテキストがhrefと同じ場合にオートリンクに変換される",
+			args: args{html: `<p><a href="https://x.com">https://x.com</a>, <a href="mailto:a@x.com"> mailto:a@x.com </a> <a href="https://x.com/a?b=1&amp;c=2">https://x.com/a?b=1&amp;c=2</a></p>`, opts: Options{CollapseSelfLinks: true}},
+			want: "<https://x.com>, <mailto:a@x.com> <https://x.com/a?b=1&c=2>",
+		},
+		{
+			name: "CollapseSelfLinksが有効でリンクのテキストがhrefと異なる場合にオートリンクに変換されない",
+			args: args{html: `<p><a href="https://x.com">x.com</a> <a href="https://x.com/">https://x.com</a> <a href="/docs">/docs</a></p>`, opts: Options{CollapseSelfLinks: true}},
+			want: "[x.com](https://x.com) [https://x.com](https://x.com/) [/docs](/docs)",
+		},
+		{
+			name: "CollapseSelfLinksが有効でBaseURLで解決したhrefがテキストと異なる場合にオートリンクに変換されない",
+			args: args{html: `<a href="/x">/x</a> <a href="https://x.com/?utm_source=y">https://x.com/?utm_source=y</a>`, opts: Options{CollapseSelfLinks: true, BaseURL: "https://e.com/", StripTrackingParams: true}},
+			want: "[/x](https://e.com/x) [https://x.com/?utm_source=y](https://x.com/)",
+		},
+		{
+			name: "CollapseSelfLinksが無効の場合にテキストがhrefと同じリンクがそのまま変換される",
+			args: args{html: `<a href="https://x.com">https://x.com</a>`},
+			want: "[https://x.com](https://x.com)",
+		},
+		// セクションの区切り
+		{
+			name: "SectionSpacingが有効の場合にセクションが空行で区切られ見出しの空行は重複しない",
+			args: args{html: `<section><h2>Intro</h2><p>Welcome.</p></section><section>Plain text<section>Nested</section></section><SECTION class="x"><h2>End</h2>Bye</SECTION>`, opts: Options{SectionSpacing: true}},
+			want: "## Intro\n\nWelcome.\n\nPlain text\n\nNested\n\n## End\n\nBye",
+		},
+		{
+			name: "SectionSpacingが無効の場合にセクションのテキストが続けて出力される",
+			args: args{html: `<section>Plain text</section><section>Nested</section>`},
+			want: "Plain textNested",
+		},
+		// 段落区切りのbr
+		{
+			name: "DoubleBreakParagraphが有効の場合に連続するbrが段落区切りになる",
+			args: args{html: "<div>First para<br><br>\nSecond para<br/> <br /><br>Third<br>line</div>", opts: Options{DoubleBreakParagraph: true}},
+			want: "First para\n\nSecond para\n\nThird  \nline",
+		},
+		{
+			name: "DoubleBreakParagraphが無効の場合に連続するbrがハードブレークのままになる",
+			args: args{html: "<div>First<br><br>Second</div>"},
+			want: "First  \n  \nSecond",
+		},
+		// 改行コード
+		{
+			name: "LineEndingがCRLFの場合に改行がCRLFになりハードブレークの空白が保持される",
+			args: args{html: "<h1>T</h1><p>a<br>b</p><ul><li>x</li><li>y</li></ul>", opts: Options{LineEnding: CRLF}},
+			want: "# T\r\n\r\na  \r\nb\r\n\r\n- x\r\n- y",
+		},
+		{
+			name: "LineEndingが不明な値の場合にLFが使われる",
+			args: args{html: "<p>a</p><p>b</p>", opts: Options{LineEnding: "\r"}},
+			want: "a\n\nb",
+		},
+		// 略語
+		{
+			name: "ExpandAbbreviationsが有効で同じabbrが2回ある場合に最初だけ展開される",
+			args: args{html: `<p><abbr title="HyperText Markup Language">HTML</abbr> and <abbr title="HyperText  Markup Language">HTML</abbr> and <abbr>CSS</abbr></p>`, opts: Options{ExpandAbbreviations: true}},
+			want: "HTML (HyperText Markup Language) and HTML and CSS",
+		},
+		{
+			name: "ExpandAbbreviationsが無効の場合にabbrはテキストのみになる",
+			args: args{html: `<p><abbr title="HyperText Markup Language">HTML</abbr></p>`},
+			want: "HTML",
+		},
+		// 引用元
+		{
+			name: "IncludeQuoteCitationsが有効でciteがある場合に引用の後に出典リンクが付く",
+			args: args{html: `<p>He said <q cite="/talks/1">ship it</q>.</p>`, opts: Options{IncludeQuoteCitations: true, BaseURL: "https://example.com/"}},
+			want: `He said "ship it" [source](https://example.com/talks/1).`,
+		},
+		{
+			name: "IncludeQuoteCitationsが有効でciteがない場合に引用符のみ付く",
+			args: args{html: `<p>He said <q>ship it</q>.</p>`, opts: Options{IncludeQuoteCitations: true}},
+			want: `He said "ship it".`,
+		},
+		{
+			name: "IncludeQuoteCitationsが無効の場合にqはテキストのみになる",
+			args: args{html: `<p>He said <q cite="/talks/1">ship it</q>.</p>`},
+			want: "He said ship it.",
+		},
+		// 長さ制限
+		{
+			name: "MaxLengthを超える場合に単語の境界で切り詰められ…が付く",
+			args: args{html: "<p>The quick brown fox jumps</p>", opts: Options{MaxLength: 14}},
+			want: "The quick…",
+		},
+		{
+			name: "MaxLengthの位置がリンクの途中の場合にリンクの前で切り詰められる",
+			args: args{html: `<p>See <a href="https://example.com/page">the docs</a> now</p>`, opts: Options{MaxLength: 12}},
+			want: "See…",
+		},
+		{
+			name: "MaxLengthの位置がコードの途中の場合にコードの前で切り詰められる",
+			args: args{html: "<p>Run <code>go test ./...</code> first</p>", opts: Options{MaxLength: 10}},
+			want: "Run…",
+		},
+		{
+			name: "MaxLengthの位置が太字の途中の場合に太字の前で切り詰められる",
+			args: args{html: "<p>Read <b>the whole guide</b> now</p>", opts: Options{MaxLength: 16}},
+			want: "Read…",
+		},
+		{
+			name: "MaxLengthの位置が入れ子の強調の途中の場合に外側の強調の前で切り詰められる",
+			args: args{html: "<p>Note <b>very <i>much</i> so</b> <del>old text</del> end</p>", opts: Options{MaxLength: 18}},
+			want: "Note…",
+		},
+		{
+			name: "MaxLengthの位置が強調の後の場合に強調が残される",
+			args: args{html: "<p>A <em>short</em> note and then more words</p>", opts: Options{MaxLength: 20}},
+			want: "A *short* note and…",
+		},
+		{
+			name: "MaxLengthの位置がコードの後の強調の途中の場合にコードの中の*は対にならない",
+			args: args{html: "<p>Use <code>a * b</code> and <em>c</em> here</p>", opts: Options{MaxLength: 18}},
+			want: "Use `a * b` and…",
+		},
+		{
+			name: "MaxLengthとReferenceLinksが有効の場合に残ったリンクの定義だけが切り詰め後に付く",
+			args: args{html: `<p>Hello <a href="https://a.example">alpha</a> and <a href="https://b.example">beta</a> text that goes on <a href="https://c.example">gamma</a></p>`, opts: Options{ReferenceLinks: true, MaxLength: 40}},
+			want: "Hello [alpha][1] and [beta][2] text…\n\n[1]: https://a.example\n[2]: https://b.example",
+		},
+		{
+			name: "MaxLengthの位置が参照リンクの途中の場合に参照リンクの前で切り詰められる",
+			args: args{html: `<p>See <a href="https://a.example">the docs</a> now</p>`, opts: Options{ReferenceLinks: true, MaxLength: 12}},
+			want: "See…",
+		},
+		{
+			name: "MaxLength以内の場合に出力が変わらない",
+			args: args{html: "<p>Short</p>", opts: Options{MaxLength: 5}},
+			want: "Short",
+		},
+		// 数式
+		{
+			name: "PreserveMathが有効の場合にインライン数式が$で囲まれる",
+			args: args{html: `<p>Energy is <span class="math">\(E=mc^2\)</span>.</p>`, opts: Options{PreserveMath: true}},
+			want: "Energy is $E=mc^2$.",
+		},
+		{
+			name: "PreserveMathが有効の場合にディスプレイ数式が$$ブロックになる",
+			args: args{html: `<p>Sum:</p><div class="math display">\[\sum_{i=1}^n x_i &lt; y\]</div><p>done</p>`, opts: Options{PreserveMath: true}},
+			want: "Sum:\n\n$$\n\\sum_{i=1}^n x_i < y\n$$\n\ndone",
+		},
+		{
+			name: "PreserveMathが有効の場合に数式内のネストしたタグが除去される",
+			args: args{html: `<p><span class="math inline"><span>a</span> &amp; <em>b</em></span></p>`, opts: Options{PreserveMath: true}},
+			want: "$a & b$",
+		},
+		{
+			name: "PreserveMathが無効の場合に数式はテキストのみになる",
+			args: args{html: `<p><span class="math">\(x\)</span></p>`},
+			want: `\(x\)`,
+		},
+		// スタイルによる強調
+		{
+			name: "InferEmphasisFromStyleが有効でfont-weight:boldの場合に太字になる",
+			args: args{html: `<span style="font-weight: bold">x</span>`, opts: Options{InferEmphasisFromStyle: true}},
+			want: "**x**",
+		},
+		{
+			name: "InferEmphasisFromStyleが有効でfont-weight:700の場合に太字になる",
+			args: args{html: `<span style="color:red;font-weight:700">x</span>`, opts: Options{InferEmphasisFromStyle: true}},
+			want: "**x**",
+		},
+		{
+			name: "InferEmphasisFromStyleが有効でfont-style:italicの場合に斜体になる",
+			args: args{html: `<span style="font-style:italic">y</span>`, opts: Options{InferEmphasisFromStyle: true}},
+			want: "*y*",
+		},
+		{
+			name: "InferEmphasisFromStyleが有効で太字と斜体の組み合わせの場合に両方適用される",
+			args: args{html: `<span style="font-weight:bold; font-style:italic">z</span>`, opts: Options{InferEmphasisFromStyle: true}},
+			want: "***z***",
+		},
+		{
+			name: "InferEmphasisFromStyleが無効の場合にスタイル付きspanはテキストのみになる",
+			args: args{html: `<span style="font-weight:bold">x</span>`},
+			want: "x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ConvertWithOptions(tt.args.html, tt.args.opts)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ConvertWithOptions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package main provides input detection.
+//
+// This file implements LooksLikeHTML, a cheap heuristic used by the CLI to
+// avoid converting JSON or plain text, which would only mangle it, and
+// DecodeHTML, which transcodes input in legacy encodings to UTF-8.
+package main
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// htmlTagDensity is the minimum number of tags per 1000 bytes for input
+// without a document marker to be treated as HTML.
+const htmlTagDensity = 1
+
+var (
+	// reDocumentMarker matches tags that only appear in HTML documents.
+	reDocumentMarker = regexp.MustCompile(`(?i)<(?:!doctype\s+html|html|head|body)\b`)
+
+	// reTagLike matches a well-formed opening, closing, or self-closing tag.
+	reTagLike = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(?:\s[^<>]*)?/?>`)
+
+	// reMetaCharset matches a <meta charset> or <meta http-equiv> tag that
+	// declares an encoding.
+	reMetaCharset = regexp.MustCompile(`(?i)<meta\b[^>]*\bcharset\s*=`)
+)
+
+// sniffLength is the number of leading bytes searched for a <meta>
+// encoding declaration, as in the HTML5 prescan.
+const sniffLength = 1024
+
+// LooksLikeHTML reports whether s appears to be HTML rather than plain text
+// or JSON.
+//
+// Preconditions:
+//   - s can be any string, including empty string
+//
+// Invariants:
+//   - The check is heuristic; it never parses s
+//
+// Postconditions:
+//   - Returns true if s contains <!doctype html>, <html>, <head>, or <body>
+//   - Returns false for input starting with { or [ without such a marker
+//   - Otherwise returns true if s has at least htmlTagDensity tags per
+//     1000 bytes, and at least one tag
+func LooksLikeHTML(s string) bool {
+	if reDocumentMarker.MatchString(s) {
+		return true
+	}
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return false
+	}
+	tags := len(reTagLike.FindAllStringIndex(trimmed, -1))
+	return tags > 0 && tags*1000 >= htmlTagDensity*len(trimmed)
+}
+
+// DecodeHTML transcodes an HTML document to a UTF-8 string.
+//
+// The encoding is taken from contentType, such as the value of an HTTP
+// Content-Type header, then from a byte order mark or a <meta charset> or
+// <meta http-equiv="Content-Type"> tag in the first 1024 bytes, following
+// the HTML5 encoding sniffing algorithm.
+//
+// Preconditions:
+//   - contentType may be empty when no header is available
+//
+// Invariants:
+//   - Input is only transcoded when its encoding is declared, or when it
+//     is not valid UTF-8; the windows-1252 guess the sniffing algorithm
+//     makes for undeclared input is never applied to valid UTF-8
+//
+// Postconditions:
+//   - UTF-8 input without conflicting declarations is returned unchanged
+//   - Shift_JIS, EUC-JP, and other declared encodings are decoded to UTF-8
+//   - Undeclared input that is not valid UTF-8 is decoded using the
+//     sniffed encoding
+//   - Returns an error only if the declared decoder fails
+func DecodeHTML(b []byte, contentType string) (string, error) {
+	_, _, certain := charset.DetermineEncoding(b, contentType)
+	declared := certain || reMetaCharset.Match(b[:min(len(b), sniffLength)])
+	if !declared && utf8.Valid(b) {
+		return string(b), nil
+	}
+	r, err := charset.NewReader(bytes.NewReader(b), contentType)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLooksLikeHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{
+			name: "html文書の場合にtrueを返す",
+			s:    "<!DOCTYPE html><html><body>Text</body></html>",
+			want: true,
+		},
+		{
+			name: "HTML断片の場合にtrueを返す",
+			s:    "<h1>Title</h1><p>Text</p>",
+			want: true,
+		},
+		{
+			name: "プレーンテキストの場合にfalseを返す",
+			s:    "Just some text, 1 < 2 and 3 > 2.",
+			want: false,
+		},
+		{
+			name: "空文字列の場合にfalseを返す",
+			s:    "",
+			want: false,
+		},
+		{
+			name: "HTMLを含むJSONの場合にfalseを返す",
+			s:    `{"body": "<p>Text</p>"}`,
+			want: false,
+		},
+		{
+			name: "長いテキストにタグが1つだけの場合にfalseを返す",
+			s:    "<b>Note</b> " + strings.Repeat("plain text ", 200),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := LooksLikeHTML(tt.s); got != tt.want {
+				t.Errorf("LooksLikeHTML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeHTML(t *testing.T) {
+	t.Parallel()
+
+	sjis, err := os.ReadFile("testdata/shift_jis.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		b           []byte
+		contentType string
+		want        string
+	}{
+		{
+			name: "meta http-equivでShift_JISが宣言された場合にUTF-8に変換される",
+			b:    sjis,
+			want: "これはShift_JISで書かれた本文です。",
+		},
+		{
+			name:        "Content-TypeでShift_JISが指定された場合にUTF-8に変換される",
+			b:           sjis[bytes.Index(sjis, []byte("<body>")):],
+			contentType: "text/html; charset=Shift_JIS",
+			want:        "日本語の見出し",
+		},
+		{
+			name: "UTF-8の場合にそのまま返される",
+			b:    []byte(`<meta charset="utf-8"><p>日本語</p>`),
+			want: "<p>日本語</p>",
+		},
+		{
+			name: "宣言のないUTF-8で先頭1024バイトがASCIIの場合にそのまま返される",
+			b:    []byte("<p>" + strings.Repeat("a", 1100) + "</p><p>日本語 café</p>"),
+			want: "<p>日本語 café</p>",
+		},
+		{
+			name: "宣言がなくUTF-8として不正な場合に推定した文字コードで変換される",
+			b:    []byte("<p>caf\xe9</p>"),
+			want: "<p>café</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeHTML(tt.b, tt.contentType)
+			if err != nil {
+				t.Fatalf("DecodeHTML() error = %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("DecodeHTML() = %q, want to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,10 +12,12 @@
 //
 // # Processing Flow
 //
-//  1. Preprocessing: Remove unwanted elements (script, style, noscript, hidden elements)
+//  1. Preprocessing: Remove unwanted elements (script, style, noscript, hidden
+//     and aria-hidden elements)
 //  2. Candidate Selection: Find all container elements (article, main, section, div)
 //  3. Scoring: Calculate a score for each candidate
-//  4. Selection: Choose the highest-scoring candidate
+//  4. Selection: Choose the highest-scoring candidate, preferring an enclosing
+//     article over nested articles such as comments
 //
 // # Score Calculation
 //
@@ -28,7 +30,7 @@
 //   - PatternScore: ±25 based on class/id pattern matching
 //   - DensityScore: (textLength - linkTextLength) / textLength * textLength / 100
 //   - ParagraphBonus: +3 per <p> element
-//   - PunctuationBonus: +1 per comma/、 (max 10), indicates prose content
+//   - PunctuationBonus: +1 per , 、 ， 。 ！ ？ (max 10), indicates prose content
 //
 // # Scoring Rationale
 //
@@ -50,9 +52,11 @@ package main
 import (
 	"bytes"
 	"regexp"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 // Scoring constants derived from Mozilla Readability algorithm.
@@ -84,14 +88,33 @@ const (
 	// Value of 3 allows 8+ paragraphs to compete with a single strong signal.
 	scoreParagraphBonus = 3.0
 
-	// scoreCommaMax caps the punctuation bonus.
+	// scoreCommaMax is the default cap of the punctuation bonus.
 	// More than 10 commas doesn't add confidence.
 	scoreCommaMax = 10
 
+	// scoreBreakMax caps the number of <br> elements rewarded by
+	// ScoringConfig.BreakBonus, so that long <br>-separated lists such as
+	// addresses or link columns gain no more than prose does.
+	scoreBreakMax = 10
+
+	// defaultWordsPerMinute is the default reading speed for words of
+	// space-separated scripts, typical of adult silent reading.
+	defaultWordsPerMinute = 200
+
+	// defaultCJKCharsPerMinute is the default reading speed for Chinese,
+	// Japanese, and Korean text, which is counted per character.
+	defaultCJKCharsPerMinute = 500
+
 	// densityDivisor normalizes text length to a reasonable score range.
 	// Dividing by 100 means 1000 chars of pure text = 10 points,
 	// keeping density scores comparable to tag-based signals.
 	densityDivisor = 100.0
+
+	// nestedArticleRatio is the fraction of the best score an enclosing
+	// <article> must reach to be preferred over a nested <article>.
+	// Nested articles are usually comments or embedded quotes, so an
+	// outer article scoring at least half as well is the real content.
+	nestedArticleRatio = 0.5
 )
 
 // Pattern matching for class/id attribute scoring.
@@ -135,12 +158,18 @@ var tagScores = map[string]float64{
 }
 
 // Tags to remove during preprocessing.
+// <dialog> modals and inert <template> contents are not page content,
+// though the parser exposes template contents as ordinary children.
 var unwantedTags = map[string]bool{
 	"script":   true,
 	"style":    true,
 	"noscript": true,
 	"iframe":   true,
 	"svg":      true,
+	"dialog":   true,
+	"template": true,
+	"select":   true,
+	"datalist": true,
 }
 
 // candidateTags defines container elements that can be content candidates.
@@ -151,6 +180,129 @@ var candidateTags = map[string]bool{
 	"div":     true,
 }
 
+// ScoringConfig tunes content extraction.
+//
+// Invariants:
+//   - DefaultScoringConfig returns the configuration used by ExtractContent
+//   - A ScoringConfig is read-only during extraction
+type ScoringConfig struct {
+	// MergeSiblings also includes sibling containers of the best candidate
+	// when they share its class attribute or score at least
+	// SiblingScoreRatio times the best score. This recovers articles split
+	// across several sibling blocks such as <div class="content-part">.
+	MergeSiblings bool
+
+	// SiblingScoreRatio is the fraction of the best score a sibling must
+	// reach to be merged by MergeSiblings. Zero merges only siblings with
+	// the same class.
+	SiblingScoreRatio float64
+
+	// PunctuationMax caps the punctuation bonus of a node. Raise it for
+	// languages such as Japanese, where long prose is marked by many 、 and 。.
+	// Zero uses the default cap of 10.
+	PunctuationMax int
+
+	// BreakBonus is added for each <br> in a node, up to 10, so that prose
+	// whose paragraphs are separated by <br> rather than <p> is not
+	// undervalued. DefaultScoringConfig uses 1, a third of the bonus for
+	// each <p>, since a paragraph break usually takes two <br>. Zero
+	// disables the bonus.
+	BreakBonus float64
+
+	// CleanTitle removes a site-name segment such as "Post | Site" or
+	// "Site - Post" from the title returned by ExtractArticle. The segment
+	// matching the page's <h1> is preferred.
+	CleanTitle bool
+
+	// WordsPerMinute is the reading speed used for Article.ReadingTime.
+	// Zero uses the default of 200.
+	WordsPerMinute int
+
+	// CJKCharsPerMinute is the reading speed used for Article.ReadingTime
+	// for Chinese, Japanese, and Korean characters. Zero uses the default
+	// of 500.
+	CJKCharsPerMinute int
+
+	// RemoveAriaHidden removes elements with aria-hidden="true" before
+	// scoring, dropping decorative icons and duplicated visual text.
+	// Screen-reader-only text is normally a sibling of the hidden icon and
+	// is kept, but content that authors wrongly placed inside an
+	// aria-hidden element is lost; disable this for such pages.
+	// DefaultScoringConfig enables it.
+	RemoveAriaHidden bool
+
+	// LinkDensityThreshold, when greater than 0, removes containers inside
+	// the selected content whose link text exceeds this fraction of their
+	// text, such as share bars and "related posts" lists. A value around
+	// 0.8 keeps prose with many inline links. Zero disables the cleanup.
+	LinkDensityThreshold float64
+
+	// ExtraUnwantedTags lists additional tag names, such as site-specific
+	// custom elements, removed before scoring along with script and style.
+	ExtraUnwantedTags []string
+
+	// KeepTags lists tag names to exempt from the default removal set,
+	// for example "svg" or "iframe" when they carry content.
+	KeepTags []string
+
+	// IncludeTitleHeading prepends the page's first <h1> to the extracted
+	// content when the selected content has no <h1> of its own, as when
+	// the title sits in a <header> outside the article body.
+	IncludeTitleHeading bool
+
+	// AllowFragments extracts content from input without a <body> tag,
+	// such as an article fragment from a CMS or feed, by parsing it as a
+	// fragment of a body and selecting a candidate among its elements.
+	// By default such input is returned unchanged.
+	AllowFragments bool
+}
+
+// isUnwanted reports whether elements named tag are removed before scoring.
+//
+// Invariants:
+//   - Tag names are compared case-insensitively
+//   - ExtraUnwantedTags takes precedence over KeepTags
+func (c *ScoringConfig) isUnwanted(tag string) bool {
+	equal := func(name string) bool { return strings.EqualFold(name, tag) }
+	if slices.ContainsFunc(c.ExtraUnwantedTags, equal) {
+		return true
+	}
+	return unwantedTags[tag] && !slices.ContainsFunc(c.KeepTags, equal)
+}
+
+// punctuationMax returns the effective punctuation bonus cap.
+func (c *ScoringConfig) punctuationMax() int {
+	if c.PunctuationMax > 0 {
+		return c.PunctuationMax
+	}
+	return scoreCommaMax
+}
+
+// wordsPerMinute returns the effective reading speed for words.
+func (c *ScoringConfig) wordsPerMinute() int {
+	if c.WordsPerMinute > 0 {
+		return c.WordsPerMinute
+	}
+	return defaultWordsPerMinute
+}
+
+// cjkCharsPerMinute returns the effective reading speed for CJK characters.
+func (c *ScoringConfig) cjkCharsPerMinute() int {
+	if c.CJKCharsPerMinute > 0 {
+		return c.CJKCharsPerMinute
+	}
+	return defaultCJKCharsPerMinute
+}
+
+// DefaultScoringConfig returns the configuration used by ExtractContent.
+func DefaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		SiblingScoreRatio: 0.5,
+		RemoveAriaHidden:  true,
+		BreakBonus:        1,
+	}
+}
+
 // ExtractContent extracts the main content from an HTML document.
 //
 // It uses a scoring algorithm inspired by Mozilla Readability to identify
@@ -167,52 +319,243 @@ var candidateTags = map[string]bool{
 //   - Returns extracted main content as HTML string
 //   - If extraction fails or no body tag exists, returns original input
 func ExtractContent(rawHTML string) string {
+	content, _ := ExtractContentOK(rawHTML)
+	return content
+}
+
+// ExtractContentOK extracts the main content from an HTML document and
+// reports whether a content candidate was found.
+//
+// Preconditions:
+//   - rawHTML can be any string, including empty or invalid HTML
+//
+// Invariants:
+//   - content is always identical to ExtractContent(rawHTML)
+//
+// Postconditions:
+//   - ok is true if a scoring candidate was selected as the main content
+//   - ok is false if the result fell back to the original input or to
+//     the whole body, letting callers apply their own fallback
+func ExtractContentOK(rawHTML string) (content string, ok bool) {
+	cfg := DefaultScoringConfig()
+	return extractContent(rawHTML, &cfg)
+}
+
+// ExtractContentWithConfig extracts the main content from an HTML document
+// using cfg.
+//
+// Preconditions:
+//   - rawHTML can be any string, including empty or invalid HTML
+//
+// Postconditions:
+//   - With DefaultScoringConfig, the result is identical to ExtractContent
+func ExtractContentWithConfig(rawHTML string, cfg ScoringConfig) string {
+	content, _ := extractContent(rawHTML, &cfg)
+	return content
+}
+
+// extractContent runs the extraction shared by all public entry points.
+//
+// Preconditions:
+//   - cfg is non-nil
+//
+// Postconditions:
+//   - Returns the extracted HTML and whether a candidate was selected
+func extractContent(rawHTML string, cfg *ScoringConfig) (string, bool) {
 	// Skip extraction for simple HTML without body tag (backward compatibility)
 	if !strings.Contains(strings.ToLower(rawHTML), "<body") {
-		return rawHTML
+		if cfg.AllowFragments {
+			return extractFragment(rawHTML, cfg)
+		}
+		return rawHTML, false
+	}
+	return extractDocument(rawHTML, cfg)
+}
+
+// extractFragment runs extraction on rawHTML parsed as the content of a
+// <body> element.
+//
+// Preconditions:
+//   - cfg is non-nil
+//
+// Invariants:
+//   - The fragment roots are scored under a detached <body> element, so
+//     no <html> or <head> is implied around them
+//
+// Postconditions:
+//   - Returns the extracted HTML and true if a candidate was selected
+//   - Returns rawHTML and false otherwise
+func extractFragment(rawHTML string, cfg *ScoringConfig) (string, bool) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	roots, err := html.ParseFragment(strings.NewReader(rawHTML), context)
+	if err != nil {
+		return rawHTML, false
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	for _, root := range roots {
+		body.AppendChild(root)
+	}
+	removeUnwantedElements(body, cfg)
+
+	nodes, ok := selectContent(body, cfg)
+	if !ok {
+		return rawHTML, false
 	}
+	return renderNodes(nodes), true
+}
 
+// extractDocument runs extraction on rawHTML whether or not it has a
+// <body> tag. The parser places fragments in an implied body.
+//
+// Preconditions:
+//   - cfg is non-nil
+//
+// Postconditions:
+//   - Returns the extracted HTML and whether a candidate was selected
+func extractDocument(rawHTML string, cfg *ScoringConfig) (string, bool) {
 	// Parse HTML
 	doc, err := html.Parse(strings.NewReader(rawHTML))
 	if err != nil {
-		return rawHTML
+		return rawHTML, false
 	}
 
 	// Remove unwanted elements
-	removeUnwantedElements(doc)
+	removeUnwantedElements(doc, cfg)
 
 	// Find body element
 	body := findElement(doc, "body")
 	if body == nil {
-		return rawHTML
+		return rawHTML, false
 	}
 
+	nodes, ok := selectContent(body, cfg)
+	if nodes == nil {
+		return rawHTML, false
+	}
+	return renderNodes(nodes), ok
+}
+
+// selectContent selects the content nodes under body.
+//
+// Preconditions:
+//   - body is a body element with unwanted elements removed
+//   - cfg is non-nil
+//
+// Postconditions:
+//   - Returns the selected nodes and true if a candidate was selected
+//   - Returns body alone and false if no candidate beat body
+//   - Returns nil and false if there is no candidate at all
+func selectContent(body *html.Node, cfg *ScoringConfig) ([]*html.Node, bool) {
 	// Find best candidate
-	candidate := findBestCandidate(body)
+	candidate := findBestCandidate(body, cfg)
 	if candidate == nil {
-		return rawHTML
+		return nil, false
+	}
+	if candidate == body {
+		return []*html.Node{body}, false
+	}
+
+	nodes := []*html.Node{candidate}
+	if cfg.MergeSiblings {
+		nodes = mergeSiblings(candidate, cfg)
+	}
+	if cfg.LinkDensityThreshold > 0 {
+		for _, n := range nodes {
+			removeLinkDenseContainers(n, cfg.LinkDensityThreshold)
+		}
 	}
 
-	// Render the candidate back to HTML
-	return renderNode(candidate)
+	if cfg.IncludeTitleHeading {
+		nodes = includeTitleHeading(body, nodes)
+	}
+	return nodes, true
+}
+
+// includeTitleHeading prepends the first <h1> of body to nodes unless one
+// of nodes already contains an <h1>.
+//
+// Postconditions:
+//   - nodes is returned unchanged if body has no <h1>
+//   - The <h1> is not moved in the tree; it is only rendered first
+func includeTitleHeading(body *html.Node, nodes []*html.Node) []*html.Node {
+	h1 := findElement(body, "h1")
+	if h1 == nil {
+		return nodes
+	}
+	for _, n := range nodes {
+		if findElement(n, "h1") != nil {
+			return nodes
+		}
+	}
+	return append([]*html.Node{h1}, nodes...)
+}
+
+// linkDenseTags lists the containers removed by removeLinkDenseContainers.
+var linkDenseTags = map[string]bool{
+	"div":     true,
+	"section": true,
+	"aside":   true,
+	"nav":     true,
+	"header":  true,
+	"footer":  true,
+	"ul":      true,
+	"ol":      true,
+}
+
+// removeLinkDenseContainers removes descendant containers of n whose link
+// density exceeds threshold.
+//
+// Preconditions:
+//   - n is the selected content node
+//   - threshold is greater than 0
+//
+// Invariants:
+//   - n itself is never removed
+//   - Paragraphs and inline elements are never removed, only linkDenseTags
+//
+// Postconditions:
+//   - No remaining descendant container has link text longer than
+//     threshold times its text length
+func removeLinkDenseContainers(n *html.Node, threshold float64) {
+	var toRemove []*html.Node
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && linkDenseTags[c.Data] {
+				textLen := len(strings.TrimSpace(getTextContent(c)))
+				if textLen > 0 && float64(getLinkTextLength(c)) > threshold*float64(textLen) {
+					toRemove = append(toRemove, c)
+					continue
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+
+	for _, node := range toRemove {
+		node.Parent.RemoveChild(node)
+	}
 }
 
 // removeUnwantedElements removes script, style, and other non-content elements.
 //
 // Preconditions:
 //   - n is a valid HTML node tree
+//   - cfg is non-nil
 //
 // Postconditions:
-//   - Unwanted elements are removed from the tree
+//   - Unwanted elements, as decided by cfg.isUnwanted, are removed from the tree
 //   - Hidden elements are removed
-func removeUnwantedElements(n *html.Node) {
+//   - If cfg.RemoveAriaHidden is set, elements with aria-hidden="true" are removed
+func removeUnwantedElements(n *html.Node, cfg *ScoringConfig) {
 	var toRemove []*html.Node
 
 	var walk func(*html.Node)
 	walk = func(node *html.Node) {
 		if node.Type == html.ElementNode {
 			// Check if tag should be removed
-			if unwantedTags[node.Data] {
+			if cfg.isUnwanted(node.Data) {
 				toRemove = append(toRemove, node)
 				return
 			}
@@ -222,12 +565,13 @@ func removeUnwantedElements(n *html.Node) {
 					toRemove = append(toRemove, node)
 					return
 				}
-				if attr.Key == "style" {
-					normalized := strings.ToLower(strings.ReplaceAll(attr.Val, " ", ""))
-					if strings.Contains(normalized, "display:none") {
-						toRemove = append(toRemove, node)
-						return
-					}
+				if cfg.RemoveAriaHidden && attr.Key == "aria-hidden" && strings.EqualFold(strings.TrimSpace(attr.Val), "true") {
+					toRemove = append(toRemove, node)
+					return
+				}
+				if attr.Key == "style" && isHiddenStyle(attr.Val) {
+					toRemove = append(toRemove, node)
+					return
 				}
 			}
 		}
@@ -246,6 +590,21 @@ func removeUnwantedElements(n *html.Node) {
 	}
 }
 
+// isHiddenStyle reports whether an inline style hides its element with
+// display:none or visibility:hidden (or collapse), ignoring whitespace,
+// case, and !important.
+//
+// opacity:0 is deliberately not treated as hidden, since pages commonly
+// start content transparent and fade it in with script.
+func isHiddenStyle(style string) bool {
+	props := parseStyle(style)
+	switch props["visibility"] {
+	case "hidden", "collapse":
+		return true
+	}
+	return props["display"] == "none"
+}
+
 // findElement finds the first element with the given tag name.
 func findElement(n *html.Node, tag string) *html.Node {
 	if n.Type == html.ElementNode && n.Data == tag {
@@ -263,11 +622,12 @@ func findElement(n *html.Node, tag string) *html.Node {
 //
 // Preconditions:
 //   - body is the body element of the document
+//   - cfg is non-nil
 //
 // Postconditions:
 //   - Returns the highest-scoring candidate node
 //   - Returns nil if no suitable candidate is found
-func findBestCandidate(body *html.Node) *html.Node {
+func findBestCandidate(body *html.Node, cfg *ScoringConfig) *html.Node {
 	var bestNode *html.Node
 	var bestScore float64 = -1000
 
@@ -276,7 +636,7 @@ func findBestCandidate(body *html.Node) *html.Node {
 		if n.Type == html.ElementNode {
 			// Only consider container elements
 			if candidateTags[n.Data] {
-				score := scoreNode(n)
+				score := scoreNode(n, cfg)
 				if score > bestScore {
 					bestScore = score
 					bestNode = n
@@ -294,7 +654,91 @@ func findBestCandidate(body *html.Node) *html.Node {
 		return body
 	}
 
-	return bestNode
+	return outermostArticle(bestNode, bestScore, cfg)
+}
+
+// outermostArticle returns the outermost <article> enclosing n that scores
+// well enough to be preferred over n.
+//
+// Nested articles such as comment threads can outscore their parent when
+// they carry positive class names. Preferring the enclosing article keeps
+// the main content together with its comments instead of a single comment.
+//
+// Preconditions:
+//   - n is the best candidate and score is its score
+//
+// Postconditions:
+//   - Returns n unchanged if n is not an <article>
+//   - Returns the outermost ancestor <article> scoring at least
+//     score * nestedArticleRatio, or n if there is none
+func outermostArticle(n *html.Node, score float64, cfg *ScoringConfig) *html.Node {
+	if n.Data != "article" {
+		return n
+	}
+	best := n
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "article" && scoreNode(p, cfg) >= score*nestedArticleRatio {
+			best = p
+		}
+	}
+	return best
+}
+
+// mergeSiblings returns best together with the sibling containers that
+// belong to the same content, in document order.
+//
+// Preconditions:
+//   - best is the selected candidate and has a parent
+//
+// Invariants:
+//   - Only candidate container elements are considered
+//   - Only the unbroken run of matching siblings around best is merged;
+//     text and comments between them do not break the run
+//
+// Postconditions:
+//   - The result always contains best
+//   - A sibling is included if it has the same non-empty class attribute
+//     as best, or scores at least cfg.SiblingScoreRatio times best's score,
+//     and every element between it and best is included too
+func mergeSiblings(best *html.Node, cfg *ScoringConfig) []*html.Node {
+	if best.Parent == nil {
+		return []*html.Node{best}
+	}
+	bestScore := scoreNode(best, cfg)
+	bestClass := getAttr(best, "class")
+	belongs := func(c *html.Node) bool {
+		switch {
+		case !candidateTags[c.Data]:
+			return false
+		case bestClass != "" && getAttr(c, "class") == bestClass:
+			return true
+		default:
+			return cfg.SiblingScoreRatio > 0 && scoreNode(c, cfg) >= bestScore*cfg.SiblingScoreRatio
+		}
+	}
+
+	first := best
+	for c := best.PrevSibling; c != nil; c = c.PrevSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if !belongs(c) {
+			break
+		}
+		first = c
+	}
+	var nodes []*html.Node
+	for c := first; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		// Elements before best were checked walking back to first
+		if c != best && !belongs(c) {
+			break
+		}
+		nodes = append(nodes, c)
+	}
+	return nodes
 }
 
 // scoreNode calculates a content score for a node.
@@ -320,10 +764,10 @@ func findBestCandidate(body *html.Node) *html.Node {
 //   - +3 points per <p> element
 //   - More paragraphs indicate article-like content
 //
-// 5. Comma Bonus:
-//   - +1 point per comma (including Japanese comma 、)
-//   - Maximum 10 points
-//   - Commas indicate prose content rather than lists or navigation
+// 5. Punctuation Bonus:
+//   - +1 point per comma or sentence mark: , 、 ， 。 ！ ？
+//   - Maximum cfg.PunctuationMax points (10 by default)
+//   - Punctuation indicates prose content rather than lists or navigation
 //
 // Example score calculation for a typical article:
 //
@@ -332,7 +776,7 @@ func findBestCandidate(body *html.Node) *html.Node {
 //	  <p>More text here.</p>      → Paragraphs: +3
 //	</article>
 //	Total: 25 + 25 + 6 + 2 + density_score = ~60+
-func scoreNode(n *html.Node) float64 {
+func scoreNode(n *html.Node, cfg *ScoringConfig) float64 {
 	var score float64
 
 	// Base score from tag
@@ -370,14 +814,32 @@ func scoreNode(n *html.Node) float64 {
 	pCount := countElements(n, "p")
 	score += float64(pCount) * scoreParagraphBonus
 
+	// Line break bonus, for prose structured with <br> instead of <p>
+	if cfg.BreakBonus != 0 {
+		score += float64(min(countElements(n, "br"), scoreBreakMax)) * cfg.BreakBonus
+	}
+
 	// Punctuation bonus (indicates prose)
-	// Counts both standard comma (,) and Japanese comma (、)
-	punctuationCount := min(strings.Count(text, ",")+strings.Count(text, "、"), scoreCommaMax)
+	// Counts the standard comma and the Japanese comma, period, and marks,
+	// since Japanese prose has few ASCII commas
+	punctuationCount := min(countPunctuation(text), cfg.punctuationMax())
 	score += float64(punctuationCount)
 
 	return score
 }
 
+// countPunctuation returns the number of prose punctuation marks in text.
+func countPunctuation(text string) int {
+	count := 0
+	for _, r := range text {
+		switch r {
+		case ',', '、', '，', '。', '！', '？':
+			count++
+		}
+	}
+	return count
+}
+
 // getAttr returns the value of an attribute on a node.
 func getAttr(n *html.Node, key string) string {
 	for _, attr := range n.Attr {
@@ -437,6 +899,15 @@ func countElements(n *html.Node, tag string) int {
 	return count
 }
 
+// renderNodes renders nodes back to a single HTML string in order.
+func renderNodes(nodes []*html.Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		sb.WriteString(renderNode(n))
+	}
+	return sb.String()
+}
+
 // renderNode renders a node back to HTML string.
 func renderNode(n *html.Node) string {
 	var buf bytes.Buffer
@@ -98,9 +98,10 @@ func BenchmarkScoreNode(b *testing.B) {
 	doc, _ := html.Parse(strings.NewReader(mediumHTML))
 	body := findElement(doc, "body")
 	article := findElement(body, "article")
+	cfg := DefaultScoringConfig()
 
 	for b.Loop() {
-		scoreNode(article)
+		scoreNode(article, &cfg)
 	}
 }
 
@@ -124,11 +125,13 @@ func BenchmarkRemoveUnwantedElements(b *testing.B) {
 		<div hidden>Hidden</div>
 	</body></html>`
 
+	cfg := DefaultScoringConfig()
+
 	for b.Loop() {
 		b.StopTimer()
 		doc, _ := html.Parse(strings.NewReader(htmlWithScripts))
 		b.StartTimer()
-		removeUnwantedElements(doc)
+		removeUnwantedElements(doc, &cfg)
 	}
 }
 
@@ -159,10 +162,11 @@ func BenchmarkCountElements(b *testing.B) {
 // BenchmarkFindBestCandidate benchmarks the findBestCandidate function.
 func BenchmarkFindBestCandidate(b *testing.B) {
 	doc, _ := html.Parse(strings.NewReader(largeHTML))
-	removeUnwantedElements(doc)
+	cfg := DefaultScoringConfig()
+	removeUnwantedElements(doc, &cfg)
 	body := findElement(doc, "body")
 
 	for b.Loop() {
-		findBestCandidate(body)
+		findBestCandidate(body, &cfg)
 	}
 }
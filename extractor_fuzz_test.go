@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+func FuzzExtractContent(f *testing.F) {
+	seeds := []string{
+		"",
+		"<html><body><article><p>Text, with commas.</p></article></body></html>",
+		"<body><nav><a href=/>Home</a></nav><div class=content><p>a, b</p></div>",
+		"<body><template><p>t</p></template><dialog>d</dialog><div aria-hidden=true>h</div>",
+		"<body><table><tr><td><table><tr><td>nested</td></tr></table>",
+		"<body>\x00<p>\x00LT\x00</p>",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, html string) {
+		ExtractContent(html)
+		ExtractArticle(html)
+		ExtractLeadImage(html)
+		ConvertReadable(html)
+	})
+}
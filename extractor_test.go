@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"strings"
 	"testing"
 
@@ -26,6 +27,16 @@ func TestExtractContent(t *testing.T) {
 			wantContains: []string{"Title", "Content"},
 			wantExcludes: []string{},
 		},
+		{
+			name: "prefers prose separated by br over short paragraphs",
+			html: `<html><body>
+				<div>The first paragraph of the story<br><br>The second paragraph continues it<br><br>
+				A third paragraph follows on<br><br>The fourth paragraph ends it</div>
+				<div><p>Sign up for our weekly newsletter today</p><p>Follow us on social media for more</p></div>
+			</body></html>`,
+			wantContains: []string{"The first paragraph", "The fourth paragraph"},
+			wantExcludes: []string{"newsletter", "social media"},
+		},
 		{
 			name: "extracts article content",
 			html: `<html><body>
@@ -65,6 +76,30 @@ func TestExtractContent(t *testing.T) {
 			wantContains: []string{"Clean content"},
 			wantExcludes: []string{"alert", "color: red"},
 		},
+		{
+			name: "removes dialog and template",
+			html: `<html><body>
+				<article>
+					<p>Clean content here, with prose.</p>
+					<dialog open><p>Subscribe to our newsletter</p></dialog>
+					<template><p>Template row, with commas, and more commas.</p></template>
+				</article>
+			</body></html>`,
+			wantContains: []string{"Clean content"},
+			wantExcludes: []string{"Subscribe", "Template row"},
+		},
+		{
+			name: "removes form controls",
+			html: `<html><body>
+				<article>
+					<p>Clean content here, with prose.</p>
+					<form><label>Sort by <select><option>Newest</option><option>Oldest</option></select></label>
+					<button>Apply filter</button></form>
+				</article>
+			</body></html>`,
+			wantContains: []string{"Clean content", "Sort by", "Apply filter"},
+			wantExcludes: []string{"Newest", "Oldest"},
+		},
 		{
 			name: "removes hidden elements",
 			html: `<html><body>
@@ -115,6 +150,23 @@ func TestExtractContent(t *testing.T) {
 			wantContains: []string{"Article Title", "Article body"},
 			wantExcludes: []string{"Footer text"},
 		},
+		{
+			name: "prefers outer article over nested comment articles",
+			html: `<html><body>
+				<article>
+					<h1>Main Story</h1>
+					<p>The main story text.</p>
+					<p>More of the main story.</p>
+					<section class="comments">
+						<article class="post"><p>First comment, nice.</p></article>
+						<article class="post"><p>Second comment, agreed.</p></article>
+						<article class="post"><p>Third comment.</p></article>
+					</section>
+				</article>
+			</body></html>`,
+			wantContains: []string{"Main Story", "main story text", "First comment"},
+			wantExcludes: []string{},
+		},
 		{
 			name: "fallback to body when no good candidate",
 			html: `<html><body>
@@ -144,6 +196,215 @@ func TestExtractContent(t *testing.T) {
 	}
 }
 
+func TestExtractContentOK(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		wantOK bool
+	}{
+		{
+			name:   "no body tag falls back to input",
+			html:   "<h1>Title</h1><p>Content</p>",
+			wantOK: false,
+		},
+		{
+			name: "no candidate falls back to body",
+			html: `<html><body>
+				<p>Just some text without containers.</p>
+			</body></html>`,
+			wantOK: false,
+		},
+		{
+			name: "article candidate is found",
+			html: `<html><body>
+				<nav><a href="#">Menu</a></nav>
+				<article><p>Main article content here.</p></article>
+			</body></html>`,
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractContentOK(tt.html)
+			if ok != tt.wantOK {
+				t.Errorf("ExtractContentOK() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if want := ExtractContent(tt.html); got != want {
+				t.Errorf("ExtractContentOK() content = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestExtractContentWithConfig(t *testing.T) {
+	twoPart := `<html><body>
+		<nav><a href="#">Home</a></nav>
+		<div class="content-part">
+			<p>First part of the story, with details.</p>
+			<p>Still the first part.</p>
+		</div>
+		<div class="content-part">
+			<p>Second part of the story, continued.</p>
+		</div>
+		<div class="widget"><a href="#">Share</a></div>
+	</body></html>`
+	shareBar := `<html><body>
+		<article>
+			<p>Prose with a <a href="/x">inline link</a>, and more words around it.</p>
+			<div class="share"><a href="#">Twitter</a> | <a href="#">Facebook</a></div>
+			<p>More prose, to keep the article long enough.</p>
+		</article>
+	</body></html>`
+
+	titleOutside := `<html><body>
+		<header><nav><a href="/">Home</a></nav><h1>The Real Title</h1></header>
+		<div class="content">
+			<h2>Background</h2>
+			<p>Body of the post, with enough prose, to be selected.</p>
+		</div>
+	</body></html>`
+
+	distantPart := `<html><body>
+		<div class="content-part">
+			<p>First part of the story, with details.</p>
+			<p>Still the first part, with more words.</p>
+		</div>
+		<div class="content-part">
+			<p>Second part of the story, continued.</p>
+		</div>
+		<aside class="ad"><a href="#">Buy now</a></aside>
+		<div class="content-part"><p>Footer notice, copyright.</p></div>
+	</body></html>`
+
+	fragment := `<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+		<article class="post">
+			<h2>Fragment Title</h2>
+			<p>An article fragment from a feed, with no body tag, but long enough.</p>
+			<p>Second paragraph, with more prose, and commas.</p>
+		</article>
+		<script>track()</script>
+		<div class="related"><a href="/a">Related</a></div>`
+
+	tests := []struct {
+		name         string
+		html         string
+		cfg          ScoringConfig
+		wantContains []string
+		wantExcludes []string
+	}{
+		{
+			name:         "default config keeps a single candidate",
+			html:         twoPart,
+			cfg:          DefaultScoringConfig(),
+			wantContains: []string{"First part"},
+			wantExcludes: []string{"Second part", "Share"},
+		},
+		{
+			name: "merge siblings includes the second part",
+			html: twoPart,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.MergeSiblings = true
+				return cfg
+			}(),
+			wantContains: []string{"First part", "Second part"},
+			wantExcludes: []string{"Share", "Home"},
+		},
+		{
+			name: "link density threshold removes share bar inside article",
+			html: shareBar,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.LinkDensityThreshold = 0.8
+				return cfg
+			}(),
+			wantContains: []string{"Prose with a", "inline link", "More prose"},
+			wantExcludes: []string{"Twitter", "Facebook"},
+		},
+		{
+			name: "merge siblings skips same-class sibling after a break in the run",
+			html: distantPart,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.MergeSiblings = true
+				return cfg
+			}(),
+			wantContains: []string{"First part", "Second part"},
+			wantExcludes: []string{"Buy now", "Footer notice"},
+		},
+		{
+			name:         "link density cleanup is off by default",
+			html:         shareBar,
+			cfg:          DefaultScoringConfig(),
+			wantContains: []string{"Prose with a", "Twitter"},
+		},
+		{
+			name: "include title heading hoists h1 from header",
+			html: titleOutside,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.IncludeTitleHeading = true
+				return cfg
+			}(),
+			wantContains: []string{"<h1>The Real Title</h1>", "Body of the post"},
+			wantExcludes: []string{"Home"},
+		},
+		{
+			name:         "title heading is not included by default",
+			html:         titleOutside,
+			cfg:          DefaultScoringConfig(),
+			wantContains: []string{"Body of the post"},
+			wantExcludes: []string{"The Real Title"},
+		},
+		{
+			name: "allow fragments extracts article from bodyless fragment",
+			html: fragment,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.AllowFragments = true
+				return cfg
+			}(),
+			wantContains: []string{`<article class="post">`, "Fragment Title", "Second paragraph"},
+			wantExcludes: []string{"Home", "Related", "track()", "<body>", "<html>"},
+		},
+		{
+			name:         "bodyless fragment is returned unchanged by default",
+			html:         fragment,
+			cfg:          DefaultScoringConfig(),
+			wantContains: []string{"Home", "Fragment Title", "Related", "track()"},
+		},
+		{
+			name: "allow fragments returns fragment unchanged without candidate",
+			html: `<p>Just a short paragraph.</p><script>x()</script>`,
+			cfg: func() ScoringConfig {
+				cfg := DefaultScoringConfig()
+				cfg.AllowFragments = true
+				return cfg
+			}(),
+			wantContains: []string{"<p>Just a short paragraph.</p><script>x()</script>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractContentWithConfig(tt.html, tt.cfg)
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("ExtractContentWithConfig() should contain %q, got:\n%s", want, got)
+				}
+			}
+
+			for _, exclude := range tt.wantExcludes {
+				if strings.Contains(got, exclude) {
+					t.Errorf("ExtractContentWithConfig() should NOT contain %q, got:\n%s", exclude, got)
+				}
+			}
+		})
+	}
+}
+
 func TestScoreNode(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -180,7 +441,8 @@ func TestScoreNode(t *testing.T) {
 				t.Fatal("failed to parse HTML")
 			}
 
-			score := scoreNode(node)
+			cfg := DefaultScoringConfig()
+			score := scoreNode(node, &cfg)
 
 			if tt.wantMin > 0 && score < tt.wantMin {
 				t.Errorf("scoreNode() = %v, want >= %v", score, tt.wantMin)
@@ -192,6 +454,62 @@ func TestScoreNode(t *testing.T) {
 	}
 }
 
+func TestScoreNode_JapanesePunctuation(t *testing.T) {
+	prose := parseFirstElement(`<div><p>今日は晴れです。散歩に行きました！楽しかったですか？はい、とても。</p></div>`)
+	links := parseFirstElement(`<div><a href="/a">ホーム</a><a href="/b">記事一覧</a><a href="/c">お問い合わせ</a></div>`)
+	if prose == nil || links == nil {
+		t.Fatal("failed to parse HTML")
+	}
+
+	cfg := DefaultScoringConfig()
+	if p, l := scoreNode(prose, &cfg), scoreNode(links, &cfg); p <= l {
+		t.Errorf("scoreNode() prose = %v, want > link list %v", p, l)
+	}
+
+	// 。！？、。 gives 5 points, capped to 2
+	cfg.PunctuationMax = 2
+	capped := scoreNode(prose, &cfg)
+	cfg.PunctuationMax = 0
+	if diff := scoreNode(prose, &cfg) - capped; diff != 3 {
+		t.Errorf("scoreNode() cap difference = %v, want 3", diff)
+	}
+}
+
+func TestScoreNode_BreakBonus(t *testing.T) {
+	article := parseFirstElement(`<div>The first paragraph of the story<br><br>
+		The second paragraph continues it<br><br>
+		A third paragraph follows on<br><br>
+		The fourth paragraph ends it</div>`)
+	nav := parseFirstElement(`<div><ul>
+		<li><a href="/">Home</a></li><li><a href="/news">News</a></li>
+		<li><a href="/sports">Sports</a></li><li><a href="/weather">Weather</a></li>
+		<li>Topics of the day</li><li>Latest stories</li></ul></div>`)
+	if article == nil || nav == nil {
+		t.Fatal("failed to parse HTML")
+	}
+
+	cfg := DefaultScoringConfig()
+	if a, n := scoreNode(article, &cfg), scoreNode(nav, &cfg); a <= n {
+		t.Errorf("scoreNode() <br> article = %v, want > nav list %v", a, n)
+	}
+
+	// Six <br> elements give 6 points at the default bonus
+	withBonus := scoreNode(article, &cfg)
+	cfg.BreakBonus = 0
+	if diff := withBonus - scoreNode(article, &cfg); math.Abs(diff-6) > 1e-9 {
+		t.Errorf("scoreNode() break bonus = %v, want 6", diff)
+	}
+
+	// The bonus is capped at 10 elements
+	long := parseFirstElement(`<div>` + strings.Repeat("line<br>", 15) + `</div>`)
+	cfg.BreakBonus = 2
+	capped := scoreNode(long, &cfg)
+	cfg.BreakBonus = 0
+	if diff := capped - scoreNode(long, &cfg); math.Abs(diff-20) > 1e-9 {
+		t.Errorf("scoreNode() capped break bonus = %v, want 20", diff)
+	}
+}
+
 func TestGetTextContent(t *testing.T) {
 	tests := []struct {
 		name string
@@ -232,10 +550,13 @@ func TestGetTextContent(t *testing.T) {
 
 func TestRemoveUnwantedElements(t *testing.T) {
 	tests := []struct {
-		name         string
-		html         string
-		wantContains string
-		wantExcludes string
+		name           string
+		html           string
+		keepAriaHidden bool
+		extraUnwanted  []string
+		keep           []string
+		wantContains   string
+		wantExcludes   string
 	}{
 		{
 			name:         "removes script",
@@ -255,6 +576,69 @@ func TestRemoveUnwantedElements(t *testing.T) {
 			wantContains: "Visible",
 			wantExcludes: "Hidden",
 		},
+		{
+			name:         "removes display none with spacing and important",
+			html:         `<div><div style="color: red; DISPLAY : none !important">Hidden</div><p>Visible</p></div>`,
+			wantContains: "Visible",
+			wantExcludes: "Hidden",
+		},
+		{
+			name:         "removes visibility hidden",
+			html:         `<div><div style="visibility: hidden">Hidden</div><p>Visible</p></div>`,
+			wantContains: "Visible",
+			wantExcludes: "Hidden",
+		},
+		{
+			name:         "removes visibility collapse",
+			html:         `<div><div style="visibility:collapse;">Hidden</div><p>Visible</p></div>`,
+			wantContains: "Visible",
+			wantExcludes: "Hidden",
+		},
+		{
+			name:         "keeps display none inside another property value",
+			html:         `<div><div style="--initial-display:none-ish; display: block">Shown</div></div>`,
+			wantContains: "Shown",
+			wantExcludes: "never-present",
+		},
+		{
+			name:         "keeps opacity zero fade-in content",
+			html:         `<div><div style="opacity: 0; transition: opacity 1s">Faded in</div></div>`,
+			wantContains: "Faded in",
+			wantExcludes: "never-present",
+		},
+		{
+			name:         "removes aria-hidden icon but keeps screen-reader text",
+			html:         `<div><button><span aria-hidden="true">★★</span><span class="sr-only">Favorite</span></button></div>`,
+			wantContains: "Favorite",
+			wantExcludes: "★★",
+		},
+		{
+			name:           "keeps aria-hidden when disabled",
+			html:           `<div><span aria-hidden="true">Duplicate</span><p>Text</p></div>`,
+			keepAriaHidden: true,
+			wantContains:   "Duplicate",
+			wantExcludes:   "never-present",
+		},
+		{
+			name:          "removes extra unwanted custom tag",
+			html:          `<div><advertisement>Buy now</advertisement><p>Keep</p></div>`,
+			extraUnwanted: []string{"ADVERTISEMENT"},
+			wantContains:  "Keep",
+			wantExcludes:  "Buy now",
+		},
+		{
+			name:         "keep tags exempts a default unwanted tag",
+			html:         `<div><noscript>Enable JS</noscript><p>Keep</p></div>`,
+			keep:         []string{"noscript"},
+			wantContains: "Enable JS",
+			wantExcludes: "never-present",
+		},
+		{
+			name:         "keeps aria-hidden false",
+			html:         `<div><span aria-hidden="false">Shown</span></div>`,
+			wantContains: "Shown",
+			wantExcludes: "never-present",
+		},
 	}
 
 	for _, tt := range tests {
@@ -264,7 +648,11 @@ func TestRemoveUnwantedElements(t *testing.T) {
 				t.Fatal("failed to parse HTML")
 			}
 
-			removeUnwantedElements(node)
+			cfg := DefaultScoringConfig()
+			cfg.RemoveAriaHidden = !tt.keepAriaHidden
+			cfg.ExtraUnwantedTags = tt.extraUnwanted
+			cfg.KeepTags = tt.keep
+			removeUnwantedElements(node, &cfg)
 			result := renderNode(node)
 
 			if !strings.Contains(result, tt.wantContains) {
@@ -0,0 +1,126 @@
+// Package main provides footnote conversion functionality.
+//
+// This file converts the common HTML footnote pattern into Markdown
+// footnote syntax:
+//
+//	<p>Claim<sup><a href="#fn1" id="ref1">1</a></sup></p>
+//	<ol class="footnotes"><li id="fn1">Source <a href="#ref1">↩</a></li></ol>
+//
+// becomes
+//
+//	Claim[^1]
+//
+//	[^1]: Source
+//
+// Footnote references are only rewritten when their target exists in a
+// footnotes list, so ordinary superscript links are left untouched.
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// reFootnoteList matches an <ol class="footnotes"> and captures its items.
+	reFootnoteList = regexp.MustCompile(`(?is)<ol[^>]*class=["'][^"']*\bfootnotes\b[^"']*["'][^>]*>(.*?)</ol>`)
+
+	// reFootnoteSection matches a <div> or <section> with class "footnotes"
+	// wrapping an ordered list, and captures the list items.
+	reFootnoteSection = regexp.MustCompile(`(?is)<(?:div|section)[^>]*class=["'][^"']*\bfootnotes\b[^"']*["'][^>]*>.*?<ol[^>]*>(.*?)</ol>.*?</(?:div|section)>`)
+
+	// reFootnoteItem matches a footnote definition and captures its id and content.
+	reFootnoteItem = regexp.MustCompile(`(?is)<li[^>]*\bid=["']([^"']+)["'][^>]*>(.*?)</li>`)
+
+	// reFootnoteRef matches a superscript link and captures its target id and text.
+	reFootnoteRef = regexp.MustCompile(`(?is)<sup[^>]*>\s*<a[^>]*href=["']#([^"']+)["'][^>]*>(.*?)</a>\s*</sup>`)
+
+	// reFootnoteBackref matches the "return to text" link inside a definition.
+	reFootnoteBackref = regexp.MustCompile(`(?is)\s*<a[^>]*href=["']#[^"']*["'][^>]*>\s*(?:↩\x{FE0E}?|&#8617;|&#x21a9;|\^|↑)\s*</a>`)
+
+	// reFootnoteLabel matches characters that are valid in a footnote label.
+	reFootnoteLabel = regexp.MustCompile(`^[\w-]+$`)
+)
+
+// footnote is a single footnote definition collected from the footnotes list.
+type footnote struct {
+	id      string
+	label   string
+	content string
+}
+
+// convertFootnotes converts footnote references and definitions to
+// Markdown footnote syntax.
+//
+// Preconditions:
+//   - s may contain <sup><a href="#id"> references and a footnotes list
+//
+// Invariants:
+//   - References whose target is not a footnote definition are unchanged
+//   - Definition content is left as HTML for the inline converters
+//
+// Postconditions:
+//   - References become [^label], where label is the reference text
+//   - The footnotes list is removed
+//   - Definitions are appended as "[^label]: content" lines in list order
+//   - If no footnotes list is found, s is returned unchanged
+func convertFootnotes(s string) string {
+	var notes []*footnote
+	for _, re := range []*regexp.Regexp{reFootnoteList, reFootnoteSection} {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			items := re.FindStringSubmatch(match)[1]
+			for _, item := range reFootnoteItem.FindAllStringSubmatch(items, -1) {
+				content := reFootnoteBackref.ReplaceAllString(item[2], "")
+				content = rePTag.ReplaceAllString(content, " ")
+				notes = append(notes, &footnote{id: item[1], content: strings.TrimSpace(content)})
+			}
+			return ""
+		})
+	}
+	if len(notes) == 0 {
+		return s
+	}
+
+	byID := make(map[string]*footnote, len(notes))
+	for _, n := range notes {
+		byID[n.id] = n
+	}
+
+	s = reFootnoteRef.ReplaceAllStringFunc(s, func(match string) string {
+		m := reFootnoteRef.FindStringSubmatch(match)
+		n, ok := byID[m[1]]
+		if !ok {
+			return match
+		}
+		if n.label == "" {
+			label := strings.Trim(reHtmlTag.ReplaceAllString(m[2], ""), "[] ")
+			if reFootnoteLabel.MatchString(label) {
+				n.label = label
+			}
+		}
+		return "[^" + footnoteLabel(n, notes) + "]"
+	})
+
+	var sb strings.Builder
+	sb.WriteString(s)
+	sb.WriteString("\n\n")
+	for _, n := range notes {
+		sb.WriteString("[^" + footnoteLabel(n, notes) + "]: " + n.content + "\n")
+	}
+	return sb.String()
+}
+
+// footnoteLabel returns the label of n, falling back to its 1-based
+// position in notes when no usable reference text was found.
+func footnoteLabel(n *footnote, notes []*footnote) string {
+	if n.label != "" {
+		return n.label
+	}
+	for i, other := range notes {
+		if other == n {
+			n.label = strconv.Itoa(i + 1)
+		}
+	}
+	return n.label
+}
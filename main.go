@@ -1,18 +1,78 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 )
 
 func main() {
-	input, err := io.ReadAll(os.Stdin)
+	inputPath := flag.String("input", "", "read HTML from `file` instead of stdin")
+	outputPath := flag.String("output", "", "write Markdown to `file` instead of stdout")
+	showStats := flag.Bool("stats", false, "print conversion statistics to stderr")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(Version())
+		return
+	}
+
+	input, err := readInput(*inputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	output, err := DecodeHTML(input, "")
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, err = os.Stdout.WriteString(Convert(string(input)))
+	switch {
+	case LooksLikeHTML(output) && *showStats:
+		var report ConversionReport
+		output, report = ConvertWithReport(output, DefaultOptions())
+		if err := writeStats(os.Stderr, report); err != nil {
+			log.Fatal(err)
+		}
+	case LooksLikeHTML(output):
+		output = Convert(output)
+	default:
+		log.Print("input does not look like HTML; passing it through unchanged")
+	}
+	err = writeOutput(*outputPath, output)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// readInput reads all of path, or stdin when path is empty.
+func readInput(path string) ([]byte, error) {
+	if path == "" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// writeOutput writes s to path, or stdout when path is empty.
+func writeOutput(path, s string) error {
+	if path == "" {
+		_, err := os.Stdout.WriteString(s)
+		return err
+	}
+	return os.WriteFile(path, []byte(s), 0o644)
+}
+
+// writeStats writes the element counts and stripped tags of report to w,
+// one "name: count" line each.
+func writeStats(w io.Writer, report ConversionReport) error {
+	e := report.Elements
+	stripped := ""
+	if len(report.UnconvertedTags) > 0 {
+		stripped = " (" + strings.Join(report.UnconvertedTags, ", ") + ")"
+	}
+	_, err := fmt.Fprintf(w, "headings: %d\nlinks: %d\nimages: %d\ntables: %d\ncode blocks: %d\nunconverted tags: %d%s\n",
+		e.Headings, e.Links, e.Images, e.Tables, e.CodeBlocks, len(report.UnconvertedTags), stripped)
+	return err
+}
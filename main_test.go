@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteStats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "counts converted elements and stripped tags",
+			html: `<h1>Title</h1><p><a href="/a">link</a> <span>s</span> <mark>m</mark></p>
+<img src="a.png" alt="a"><table><tr><th>A</th></tr><tr><td>1</td></tr></table>
+<pre><code>x</code></pre>`,
+			want: "headings: 1\nlinks: 1\nimages: 1\ntables: 1\ncode blocks: 1\nunconverted tags: 2 (span, mark)\n",
+		},
+		{
+			name: "omits the tag list when nothing was stripped",
+			html: "<p>Text</p>",
+			want: "headings: 0\nlinks: 0\nimages: 0\ntables: 0\ncode blocks: 0\nunconverted tags: 0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			md, report := ConvertWithReport(tt.html, Options{})
+			var sb strings.Builder
+			if err := writeStats(&sb, report); err != nil {
+				t.Fatalf("writeStats() error = %v", err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("writeStats() = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(md, "headings:") {
+				t.Errorf("markdown %q contains stats", md)
+			}
+		})
+	}
+}
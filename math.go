@@ -0,0 +1,134 @@
+// Package main provides math passthrough functionality.
+//
+// This file converts math containers emitted by MathJax and KaTeX
+// pipelines into TeX delimited for Markdown renderers:
+//
+//	<span class="math">\(E=mc^2\)</span>
+//	<div class="math display">\[\sum_i x_i\]</div>
+//
+// becomes
+//
+//	$E=mc^2$
+//
+//	$$
+//	\sum_i x_i
+//	$$
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// reMathOpen matches an opening <span> or <div> and captures its name
+	// and attributes. Whether it is a math container is decided by class.
+	reMathOpen = regexp.MustCompile(`(?i)<(span|div)\b([^>]*)>`)
+
+	// reSpanTag and reDivTag match the tags counted to find the end of a
+	// math container, which may contain nested markup.
+	reSpanTag = regexp.MustCompile(`(?i)<(/?)span\b[^>]*>`)
+	reDivTag  = regexp.MustCompile(`(?i)<(/?)div\b[^>]*>`)
+)
+
+// mathDelimiters lists the TeX delimiter pairs stripped from math content
+// before it is wrapped in $ or $$. Longer delimiters come first.
+var mathDelimiters = [][2]string{
+	{`\(`, `\)`},
+	{`\[`, `\]`},
+	{"$$", "$$"},
+	{"$", "$"},
+}
+
+// convertMath converts math containers to $ or $$ delimited TeX.
+//
+// Preconditions:
+//   - s is HTML before block conversion
+//
+// Invariants:
+//   - A container is a <span> or <div> whose class includes "math";
+//     other spans and divs are unchanged
+//   - Nested tags inside a container are stripped and entities decoded
+//
+// Postconditions:
+//   - A <div>, or a container whose class includes "display", becomes a
+//     $$ block surrounded by blank lines
+//   - Any other container becomes inline $...$
+//   - Angle brackets in the TeX are escaped to survive cleanup
+func convertMath(s string) string {
+	var sb strings.Builder
+	last := 0
+	for {
+		loc := reMathOpen.FindStringSubmatchIndex(s[last:])
+		if loc == nil {
+			break
+		}
+		start, openEnd := last+loc[0], last+loc[1]
+		name := strings.ToLower(s[last+loc[2] : last+loc[3]])
+		class, _ := tagAttr(s[last+loc[4]:last+loc[5]], "class")
+		classes := strings.Fields(strings.ToLower(class))
+		if !slices.Contains(classes, "math") {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		reTag := reSpanTag
+		if name == "div" {
+			reTag = reDivTag
+		}
+		closeStart, closeEnd := matchingCloseTag(s[openEnd:], reTag)
+		if closeStart < 0 {
+			sb.WriteString(s[last:openEnd])
+			last = openEnd
+			continue
+		}
+
+		tex := mathTeX(s[openEnd : openEnd+closeStart])
+		sb.WriteString(s[last:start])
+		if name == "div" || slices.Contains(classes, "display") {
+			sb.WriteString("\n\n$$\n" + tex + "\n$$\n\n")
+		} else {
+			sb.WriteString("$" + strings.Join(strings.Fields(tex), " ") + "$")
+		}
+		last = openEnd + closeEnd
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// matchingCloseTag returns the start and end offsets in s of the closing
+// tag that balances an already consumed opening tag matched by reTag.
+// It returns -1, -1 if the tag is never closed.
+func matchingCloseTag(s string, reTag *regexp.Regexp) (int, int) {
+	depth := 1
+	for _, m := range reTag.FindAllStringSubmatchIndex(s, -1) {
+		if m[3] > m[2] {
+			depth--
+		} else {
+			depth++
+		}
+		if depth == 0 {
+			return m[0], m[1]
+		}
+	}
+	return -1, -1
+}
+
+// mathTeX extracts the TeX source from the inner HTML of a math container.
+//
+// Postconditions:
+//   - Tags are removed and entities decoded
+//   - One pair of enclosing TeX delimiters, if present, is removed
+//   - Leading and trailing whitespace is trimmed
+func mathTeX(inner string) string {
+	tex := strings.TrimSpace(decodeHTMLEntities(reHtmlTag.ReplaceAllString(inner, "")))
+	for _, d := range mathDelimiters {
+		if len(tex) >= len(d[0])+len(d[1]) && strings.HasPrefix(tex, d[0]) && strings.HasSuffix(tex, d[1]) {
+			tex = strings.TrimSpace(tex[len(d[0]) : len(tex)-len(d[1])])
+			break
+		}
+	}
+	return escapeAngles(tex)
+}
@@ -0,0 +1,52 @@
+// Package main provides conversion of parsed HTML trees.
+//
+// This file implements ConvertNode and ConvertNodeWithOptions for callers
+// that already hold a tree parsed by golang.org/x/net/html, such as one
+// produced by their own crawler or filtered before conversion.
+//
+// The converter works on HTML text, not on a tree, so there is no DOM entry
+// point. Both functions are convenience wrappers that render the tree with
+// html.Render and convert the result. They have no performance benefit
+// over rendering the tree yourself and calling Convert.
+package main
+
+import (
+	"golang.org/x/net/html"
+)
+
+// ConvertNode transforms a parsed HTML node and its subtree into Markdown.
+// It is a render-and-convert wrapper, equivalent to Convert on the HTML that
+// html.Render writes for n.
+//
+// Preconditions:
+//   - n may be nil, a document, an element, or a text node
+//
+// Invariants:
+//   - n is rendered back to HTML and passed through the same pipeline as
+//     Convert, so both produce identical Markdown for equivalent input
+//   - n is not modified
+//
+// Postconditions:
+//   - Returns an empty string for a nil node
+//   - Only n's subtree is converted; its siblings and ancestors are ignored
+//   - Content extraction runs only when n is or contains the <body>
+func ConvertNode(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	return Convert(renderNode(n))
+}
+
+// ConvertNodeWithOptions is ConvertNode using opts.
+//
+// Postconditions:
+//   - Returns an empty string for a nil node
+//   - The result is identical to ConvertWithOptions on the HTML that
+//     html.Render writes for n
+//   - With DefaultOptions(), the result is identical to ConvertNode
+func ConvertNodeWithOptions(n *html.Node, opts Options) string {
+	if n == nil {
+		return ""
+	}
+	return ConvertWithOptions(renderNode(n), opts)
+}
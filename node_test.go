@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestConvertNode(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<nav><a href="/">Home</a></nav>
+		<article id="post"><h1>Title</h1><p>Body with <strong>bold</strong> text, and more.</p></article>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	article := findElement(doc, "article")
+
+	// The parser repairs overlapping tags the same way fixMisnestedTags does
+	overlapping, err := html.Parse(strings.NewReader(`<p><b>bold <i>both</b> italic</i></p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		node *html.Node
+		want string
+	}{
+		{
+			name: "nil node",
+			node: nil,
+			want: "",
+		},
+		{
+			name: "element subtree",
+			node: article,
+			want: "# Title\n\nBody with **bold** text, and more.",
+		},
+		{
+			name: "text node",
+			node: article.LastChild.FirstChild,
+			want: "Body with",
+		},
+		{
+			name: "document runs content extraction",
+			node: doc,
+			want: "# Title\n\nBody with **bold** text, and more.",
+		},
+		{
+			name: "overlapping emphasis",
+			node: findElement(overlapping, "p"),
+			want: "**bold *both*** *italic*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertNode(tt.node); got != tt.want {
+				t.Errorf("ConvertNode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertNodeWithOptions(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<ul><li>One</li><li>Two</li></ul><h2>Next</h2>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := findElement(doc, "ul")
+
+	tests := []struct {
+		name string
+		node *html.Node
+		opts Options
+		want string
+	}{
+		{
+			name: "nil node",
+			node: nil,
+			want: "",
+		},
+		{
+			name: "options apply to the subtree",
+			node: list,
+			opts: Options{BulletMarker: '*', LooseLists: true},
+			want: "* One\n\n* Two",
+		},
+		{
+			name: "default options match ConvertNode",
+			node: doc,
+			opts: DefaultOptions(),
+			want: ConvertNode(doc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConvertNodeWithOptions(tt.node, tt.opts); got != tt.want {
+				t.Errorf("ConvertNodeWithOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+// Package main provides conversion options.
+//
+// This file defines Options, which tunes the output of ConvertWithOptions,
+// and Converter, which holds Options built from functional options for reuse.
+// DefaultOptions returns the Options used by Convert.
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// SummaryStyle selects how the text of a <details> <summary> is rendered.
+type SummaryStyle int
+
+const (
+	// SummaryBold wraps the summary text in <b> so it stands out.
+	SummaryBold SummaryStyle = iota
+	// SummaryPlain emits the summary text unchanged.
+	SummaryPlain
+)
+
+// HeadingStyle selects the Markdown syntax used for headings.
+type HeadingStyle int
+
+const (
+	// ATX writes headings as "# Title".
+	ATX HeadingStyle = iota
+	// Setext underlines level 1 and 2 headings with = and -.
+	// Deeper levels fall back to ATX, since Setext has no syntax for them.
+	Setext
+)
+
+// CodeBlockStyle selects the Markdown syntax used for code blocks.
+type CodeBlockStyle int
+
+const (
+	// Fenced wraps code blocks in ``` fences.
+	Fenced CodeBlockStyle = iota
+	// Indented indents each line of a code block by four spaces, for tools
+	// that predate fences. Indented blocks cannot carry a language, so any
+	// language information is lost.
+	Indented
+)
+
+// CommentMode selects what happens to HTML comments.
+type CommentMode int
+
+const (
+	// Strip removes comments.
+	Strip CommentMode = iota
+	// HTMLPassthrough keeps comments as <!-- --> in the output, where
+	// Markdown renderers hide them.
+	HTMLPassthrough
+	// MarkdownLink emits each comment as a "[//]: # (text)" link reference
+	// definition, which renders as nothing. Whitespace in the text is
+	// collapsed and the comment is placed on its own line.
+	MarkdownLink
+)
+
+// RubyStyle selects how <ruby> annotations such as furigana are rendered.
+type RubyStyle int
+
+const (
+	// RubyParens writes the reading in parentheses after its base text,
+	// as in 漢字(かんじ).
+	RubyParens RubyStyle = iota
+	// RubyBaseOnly keeps the base text and drops the reading.
+	RubyBaseOnly
+	// RubyHTML keeps <ruby> and <rt> tags for renderers that display them.
+	RubyHTML
+)
+
+// TableCellBreak selects how <br> inside a pipe table cell is rendered.
+// A Markdown hard break would end the table row, so one cannot be used.
+type TableCellBreak int
+
+const (
+	// HTMLBreak keeps <br> as an HTML tag, which GFM renders as a line
+	// break within the cell.
+	HTMLBreak TableCellBreak = iota
+	// SpaceBreak replaces <br> and the whitespace around it with a space.
+	SpaceBreak
+	// StripBreak removes <br>, joining the text on either side.
+	StripBreak
+)
+
+// DataURIMode selects what happens to images whose src is a data: URI.
+type DataURIMode int
+
+const (
+	// DataURIKeep emits data: URI images unchanged.
+	DataURIKeep DataURIMode = iota
+	// DataURIStrip drops data: URI images from the output.
+	DataURIStrip
+	// DataURIPlaceholder replaces the data: URI with the fixed
+	// destination "data-image", keeping the alt text.
+	DataURIPlaceholder
+)
+
+// dataImagePlaceholder is the image destination written by DataURIPlaceholder.
+const dataImagePlaceholder = "data-image"
+
+// CaptionPosition selects where a <figure>'s caption is placed.
+type CaptionPosition int
+
+const (
+	// CaptionBelow places the caption after the figure content.
+	CaptionBelow CaptionPosition = iota
+	// CaptionAbove places the caption before the figure content.
+	CaptionAbove
+)
+
+// ReferenceLabelStyle selects the labels of reference-style links.
+type ReferenceLabelStyle int
+
+const (
+	// NumericLabels labels references [1], [2], ... in order of first use.
+	NumericLabels ReferenceLabelStyle = iota
+	// TextLabels labels references with a slug of the link text, such as
+	// [example-site], adding -2, -3, ... when texts collide.
+	TextLabels
+)
+
+// HeadingAnchors selects how a heading's id attribute is kept, so that
+// links to "#id" still resolve after conversion.
+type HeadingAnchors int
+
+const (
+	// AnchorNone drops heading ids.
+	AnchorNone HeadingAnchors = iota
+	// AnchorAttr appends the id as a "{#id}" attribute, the syntax used by
+	// Pandoc, kramdown, and Markdown Extra.
+	AnchorAttr
+	// AnchorHTML writes an empty <a id="id"></a> before the heading.
+	AnchorHTML
+)
+
+// Line endings accepted by Options.LineEnding.
+const (
+	// LF ends lines with "\n", the default.
+	LF = "\n"
+	// CRLF ends lines with "\r\n", as expected by Windows tools.
+	CRLF = "\r\n"
+)
+
+// Options configures the Markdown produced by ConvertWithOptions.
+//
+// Invariants:
+//   - The zero value is valid; DefaultOptions returns the options used
+//     by Convert
+//   - Options are read-only during conversion
+type Options struct {
+	// LooseLists inserts a blank line between list items.
+	// When false, items are separated by a single newline (tight list).
+	LooseLists bool
+
+	// HeadingTransform, if non-nil, is applied to the text of each heading
+	// before it is emitted. The text may still contain inline HTML that is
+	// converted afterward. Use it for casing or sanitization.
+	HeadingTransform func(string) string
+
+	// AllowedTags restricts conversion to the listed tag names
+	// (case-insensitive). All other tags are stripped before conversion,
+	// keeping only their text; raw HTML is never preserved.
+	// When empty, all supported tags are converted.
+	AllowedTags []string
+
+	// PreserveNBSP decodes &nbsp; to the U+00A0 non-breaking space character
+	// and exempts it from whitespace collapsing. When false, &nbsp; becomes
+	// a regular space.
+	PreserveNBSP bool
+
+	// Footnotes converts <sup><a href="#id"> references whose target is in
+	// an <ol class="footnotes"> (or a <div>/<section class="footnotes">)
+	// into Markdown footnotes: [^1] inline and "[^1]: text" at the end.
+	Footnotes bool
+
+	// InferEmphasisFromStyle converts <span> tags whose inline style sets
+	// font-weight:bold (or 700+) or font-style:italic to ** and * emphasis,
+	// as emitted by WYSIWYG editors instead of <strong> and <em>.
+	InferEmphasisFromStyle bool
+
+	// ContinueOrderedNumbering makes an <ol> without a start attribute
+	// continue numbering from the previous <ol> at the same nesting depth.
+	// When false, each <ol> starts at its start attribute or 1.
+	ContinueOrderedNumbering bool
+
+	// StripTrackingParams removes tracking query parameters from link and
+	// image URLs, producing clean archival Markdown.
+	StripTrackingParams bool
+
+	// TrackingParams lists the query parameter name prefixes removed by
+	// StripTrackingParams. When empty, "utm_", "fbclid", and "gclid" are used.
+	TrackingParams []string
+
+	// SummaryStyle controls how <summary> text inside a preserved <details>
+	// block is rendered. The default is SummaryBold.
+	SummaryStyle SummaryStyle
+
+	// BulletMarker is the marker for unordered list items: '-', '*', or '+'.
+	// Any other value, including the zero value, uses '-'.
+	BulletMarker rune
+
+	// HeadingStyle selects ATX (default) or Setext headings.
+	HeadingStyle HeadingStyle
+
+	// BaseURL, if set, is used to resolve relative link and image URLs.
+	// Fragment-only links such as "#top" are left unchanged.
+	BaseURL string
+
+	// ImagesAsHTML emits images as <img> tags instead of ![alt](src),
+	// preserving attributes such as loading and decoding for
+	// HTML-capable Markdown renderers.
+	ImagesAsHTML bool
+
+	// ImageKeepAttrs lists the attributes, besides src and alt, copied to
+	// <img> tags emitted by ImagesAsHTML. When empty, loading and decoding
+	// are kept.
+	ImageKeepAttrs []string
+
+	// TabWidth, when greater than 0, expands leading tabs in code blocks to
+	// that many spaces. Prose is not affected. When 0, tabs are kept.
+	TabWidth int
+
+	// HeadingOffset shifts every heading level by this amount, clamped to
+	// levels 1 through 6. With 1, <h1> becomes ## so converted content can
+	// nest under an existing top-level heading.
+	HeadingOffset int
+
+	// CodeBlockStyle selects Fenced (default) or Indented code blocks.
+	CodeBlockStyle CodeBlockStyle
+
+	// SingleColumnTableAsList converts tables whose rows all have exactly
+	// one cell to an unordered list instead of a one-column table.
+	SingleColumnTableAsList bool
+
+	// SingleColumnHeaderLevel, when between 1 and 6, renders the <th>
+	// header cell of a table converted by SingleColumnTableAsList as a
+	// heading of that level. Otherwise the header becomes the first item.
+	SingleColumnHeaderLevel int
+
+	// KeepComments selects whether HTML comments are stripped (default),
+	// passed through, or emitted as Markdown link comments.
+	KeepComments CommentMode
+
+	// RubyStyle controls how <ruby> annotations are rendered.
+	// The default is RubyParens. <rp> fallback parentheses are always dropped.
+	RubyStyle RubyStyle
+
+	// GenerateTOC adds a table of contents built from the headings, as a
+	// nested list of links to GitHub-style anchors. It replaces a
+	// <!-- TOC --> comment if present, and is prepended otherwise.
+	GenerateTOC bool
+
+	// CollapseWhitespace collapses runs of spaces and tabs in prose to a
+	// single space as a final step, removing doubles introduced during
+	// conversion. Code spans, code blocks, indentation, and hard-break
+	// trailing spaces are left intact.
+	CollapseWhitespace bool
+
+	// PreserveMath emits the TeX inside <span class="math"> and
+	// <div class="math"> containers as $...$ inline math or $$...$$
+	// display math, for MathJax and KaTeX renderers. Containers whose
+	// class includes "display" are also treated as display math.
+	PreserveMath bool
+
+	// LineEnding is the line terminator of the output: LF or CRLF. It is
+	// applied as the final step, so hard breaks become two spaces followed
+	// by CRLF. The empty string means LF. Any other value falls back to LF
+	// and is reported as a warning by ConvertWithReport.
+	LineEnding string
+
+	// StripHeadingNumbers removes leading section numbers such as "1. "
+	// or "2) " from headings, for CMS output that numbers them by hand.
+	// Numbers of four or more digits, such as years, are kept.
+	StripHeadingNumbers bool
+
+	// TableCellBreak controls how <br> inside table cells is rendered.
+	// The default is HTMLBreak.
+	TableCellBreak TableCellBreak
+
+	// MaxLength, when greater than 0, truncates the output to at most this
+	// many characters for previews. The cut is made at a word boundary,
+	// never inside a link or code, and marked with a trailing "…". With
+	// ReferenceLinks, the definitions of the links that remain are kept
+	// after the cut and do not count toward the limit.
+	MaxLength int
+
+	// IncludeQuoteCitations renders <q> quotations in double quotes and,
+	// when the <q> has a cite attribute, follows the quote with a
+	// [source](url) link to preserve attribution. When false, <q> is
+	// reduced to its text.
+	IncludeQuoteCitations bool
+
+	// NormalizeHeadingBase shifts all headings so the highest level present
+	// becomes #, for content extracted from below the page's <h1>.
+	// HeadingOffset is applied after normalization, so an offset of 1
+	// makes the highest level ##.
+	NormalizeHeadingBase bool
+
+	// TaskLists converts <input type="checkbox"> to [x] or [ ]. At the
+	// start of a list item this produces a GFM task list item; a bare
+	// checkbox outside a list is marked inline before its label text.
+	TaskLists bool
+
+	// DataURIImages controls images with inline data: URI sources, whose
+	// base64 payload can make a single Markdown line enormous.
+	// The default is DataURIKeep.
+	DataURIImages DataURIMode
+
+	// FigureCaption places the <figcaption> of each <figure> below
+	// (default) or above its content, whatever the source order.
+	FigureCaption CaptionPosition
+
+	// ReferenceLinks writes links as [text][label] with the "[label]: url"
+	// definitions collected at the end of the document, instead of inline
+	// [text](url) links.
+	ReferenceLinks bool
+
+	// ReferenceLabelStyle selects NumericLabels (default) or TextLabels
+	// for ReferenceLinks.
+	ReferenceLabelStyle ReferenceLabelStyle
+
+	// ExpandAbbreviations appends the title of an <abbr> in parentheses,
+	// as in "HTML (HyperText Markup Language)", on its first occurrence
+	// only. Later occurrences and <abbr> without a title keep just their
+	// text, which is also the behavior when this is false.
+	ExpandAbbreviations bool
+
+	// DedentCode removes the indentation shared by every non-blank line
+	// of a code block, left over from the formatting of the HTML source.
+	// Relative indentation is kept.
+	DedentCode bool
+
+	// DoubleBreakParagraph treats runs of two or more <br> tags as
+	// paragraph breaks, so prose separated only by <br><br> gets blank
+	// lines instead of consecutive hard breaks. Tables and <pre> blocks
+	// are not affected.
+	DoubleBreakParagraph bool
+
+	// HeadingAnchors controls whether heading ids are kept as anchors.
+	// The default is AnchorNone.
+	HeadingAnchors HeadingAnchors
+
+	// ComplexTableAsHTML keeps tables that a pipe table cannot represent
+	// as HTML: tables with a rowspan, with paragraphs or lists in a cell,
+	// or with more than one header row. Only structural and basic inline
+	// tags are kept, with the rowspan, colspan, scope, and href attributes.
+	// Other tables still become pipe tables.
+	ComplexTableAsHTML bool
+
+	// SeparatorClasses lists class names, such as "separator", of <div>
+	// elements that editors insert as section separators. Each such div
+	// becomes a horizontal rule, followed by its content, such as a
+	// centered image, if any. Class names are matched case-insensitively.
+	// The default is empty, which recognizes no separators.
+	SeparatorClasses []string
+
+	// Autolink wraps bare http and https URLs in text as CommonMark
+	// autolinks, such as <https://example.com>, so they are clickable.
+	// URLs in links, code, and preformatted text are left unchanged.
+	Autolink bool
+
+	// Admonitions converts <div>, <aside>, and <section> elements whose
+	// class names an admonition into GFM alert blockquotes, such as
+	// "> [!WARNING]" for <div class="warning">. A leading element with
+	// class admonition-title, as written by Sphinx and MkDocs, is dropped.
+	Admonitions bool
+
+	// AdmonitionClasses maps class names to GFM alert types, such as
+	// "danger" to "CAUTION", for Admonitions. Class names are matched
+	// case-insensitively. When empty, the classes note, tip, important,
+	// warning, and caution map to the alert of the same name.
+	AdmonitionClasses map[string]string
+
+	// StripInvisibleChars removes soft hyphens (U+00AD and &shy;),
+	// zero-width spaces (U+200B), word joiners (U+2060), and byte order
+	// marks (U+FEFF) from text other than code, since they break searching
+	// and word matching in the output. An escaped &amp;shy; is text, not a
+	// soft hyphen, and is kept. DefaultOptions enables it.
+	StripInvisibleChars bool
+
+	// SectionSpacing separates the content of each <section> from the
+	// content around it with a blank line, so text directly inside
+	// adjacent sections does not run together. Blank lines around
+	// headings and paragraphs are not doubled.
+	SectionSpacing bool
+
+	// CollapseSelfLinks writes a link whose text is its own URL, such as
+	// <a href="https://x.com">https://x.com</a>, as the autolink
+	// <https://x.com> instead of [https://x.com](https://x.com). The text
+	// must equal the destination after BaseURL resolution and tracking
+	// parameter removal, so the visible text never changes.
+	// DefaultOptions enables it.
+	CollapseSelfLinks bool
+}
+
+// DefaultOptions returns the options used by Convert.
+func DefaultOptions() Options {
+	return Options{
+		StripInvisibleChars: true,
+		CollapseSelfLinks:   true,
+	}
+}
+
+// bulletMarker returns the unordered list marker to emit.
+func (o *Options) bulletMarker() string {
+	switch o.BulletMarker {
+	case '*', '+':
+		return string(o.BulletMarker)
+	}
+	return "-"
+}
+
+// headingLevel returns the Markdown level for an HTML heading level after
+// applying HeadingOffset, clamped to 1 through 6.
+func (o *Options) headingLevel(level int) int {
+	return min(max(level+o.HeadingOffset, 1), 6)
+}
+
+// lineEnding returns the line terminator to emit and whether
+// LineEnding holds a recognized value.
+func (o *Options) lineEnding() (string, bool) {
+	switch o.LineEnding {
+	case "", LF:
+		return LF, true
+	case CRLF:
+		return CRLF, true
+	}
+	return LF, false
+}
+
+// allowsTag reports whether tag may be processed by its converter.
+//
+// Preconditions:
+//   - tag is a tag name without angle brackets
+//
+// Postconditions:
+//   - Returns true for every tag when AllowedTags is empty
+func (o *Options) allowsTag(tag string) bool {
+	if len(o.AllowedTags) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(o.AllowedTags, func(allowed string) bool {
+		return strings.EqualFold(allowed, tag)
+	})
+}
+
+// isSeparator reports whether class, the class attribute of a <div>,
+// includes one of SeparatorClasses.
+func (o *Options) isSeparator(class string) bool {
+	return slices.ContainsFunc(strings.Fields(class), func(name string) bool {
+		return slices.ContainsFunc(o.SeparatorClasses, func(sep string) bool {
+			return strings.EqualFold(sep, name)
+		})
+	})
+}
+
+// admonitionType returns the GFM alert type for class, the class
+// attribute of an element, or "" if no class names an admonition.
+func (o *Options) admonitionType(class string) string {
+	classes := o.AdmonitionClasses
+	if len(classes) == 0 {
+		classes = defaultAdmonitionClasses
+	}
+	for _, name := range strings.Fields(class) {
+		for c, alert := range classes {
+			if strings.EqualFold(c, name) {
+				return strings.ToUpper(alert)
+			}
+		}
+	}
+	return ""
+}
+
+// Converter converts HTML to Markdown with a fixed set of options.
+//
+// Invariants:
+//   - Options are fixed at construction
+//   - A Converter is safe for concurrent use
+type Converter struct {
+	opts Options
+}
+
+// Option configures a Converter built by NewConverter.
+type Option func(*Options)
+
+// NewConverter returns a Converter configured by opts.
+//
+// Preconditions:
+//   - opts may be empty
+//
+// Invariants:
+//   - opts are applied in order to DefaultOptions; later options override
+//     earlier ones
+//
+// Postconditions:
+//   - With no options, the Converter produces the same output as Convert
+func NewConverter(opts ...Option) *Converter {
+	c := &Converter{opts: DefaultOptions()}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	return c
+}
+
+// Convert transforms an HTML string into Markdown using the Converter's options.
+//
+// Postconditions:
+//   - The result is identical to ConvertWithOptions with the same Options
+func (c *Converter) Convert(html string) string {
+	opts := c.opts
+	return convert(html, &opts, nil)
+}
+
+// WithOptions replaces all options with o.
+// It is useful as a first option when starting from an existing Options value.
+func WithOptions(o Options) Option {
+	return func(opts *Options) {
+		*opts = o
+	}
+}
+
+// WithBulletMarker sets the unordered list marker to '-', '*', or '+'.
+func WithBulletMarker(marker rune) Option {
+	return func(opts *Options) {
+		opts.BulletMarker = marker
+	}
+}
+
+// WithHeadingStyle sets the heading syntax to ATX or Setext.
+func WithHeadingStyle(style HeadingStyle) Option {
+	return func(opts *Options) {
+		opts.HeadingStyle = style
+	}
+}
+
+// WithBaseURL sets the URL against which relative links and images are resolved.
+func WithBaseURL(base string) Option {
+	return func(opts *Options) {
+		opts.BaseURL = base
+	}
+}
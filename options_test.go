@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewConverter(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		html string
+		opts []Option
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "オプションなしの場合にConvertと同じ結果になる",
+			args: args{html: "<h1>Title</h1><ul><li>A</li></ul>"},
+			want: "# Title\n\n- A",
+		},
+		{
+			name: "WithBulletMarkerが指定された場合にその記号で箇条書きになる",
+			args: args{html: "<ul><li>A</li><li>B<ul><li>C</li></ul></li></ul>", opts: []Option{WithBulletMarker('*')}},
+			want: "* A\n* B\n  * C",
+		},
+		{
+			name: "WithBulletMarkerに不正な記号が指定された場合にハイフンになる",
+			args: args{html: "<ul><li>A</li></ul>", opts: []Option{WithBulletMarker('x')}},
+			want: "- A",
+		},
+		{
+			name: "WithHeadingStyleがSetextの場合にh1とh2に下線が付く",
+			args: args{html: "<h1>Title</h1><h2>Hi</h2><h3>Deep</h3>", opts: []Option{WithHeadingStyle(Setext)}},
+			want: "Title\n=====\n\nHi\n---\n\n### Deep",
+		},
+		{
+			name: "WithBaseURLが指定された場合に相対URLが解決される",
+			args: args{
+				html: `<a href="/about">About</a> <a href="#top">Top</a> <img src="img/a.png" alt="a">`,
+				opts: []Option{WithBaseURL("https://example.com/blog/post")},
+			},
+			want: "[About](https://example.com/about) [Top](#top) ![a](https://example.com/blog/img/a.png)",
+		},
+		{
+			name: "WithOptionsの後のオプションが優先される",
+			args: args{
+				html: "<ul><li>A</li><li>B</li></ul>",
+				opts: []Option{WithOptions(Options{LooseLists: true, BulletMarker: '+'}), WithBulletMarker('*')},
+			},
+			want: "* A\n\n* B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewConverter(tt.args.opts...).Convert(tt.args.html)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Converter.Convert() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
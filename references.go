@@ -0,0 +1,151 @@
+// Package main provides reference-style link conversion.
+//
+// This file collects the links of a document when Options.ReferenceLinks
+// is set, so that
+//
+//	<a href="https://example.com">Example</a>
+//
+// becomes
+//
+//	[Example][1]
+//
+//	[1]: https://example.com
+//
+// with the definitions gathered at the end of the document.
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// reReferenceLink matches a reference link or image and captures its label.
+	reReferenceLink = regexp.MustCompile(`!?\[[^\]]*\]\[([^\]]+)\]`)
+
+	// reDefinition matches a definition line written by definitions and
+	// captures its label.
+	reDefinition = regexp.MustCompile(`^\[([^\]]+)\]: \S`)
+)
+
+// linkReferences assigns reference labels to link destinations.
+//
+// Invariants:
+//   - Each label maps to exactly one destination
+//   - Labels are listed in order of first use
+type linkReferences struct {
+	style  ReferenceLabelStyle
+	labels []string
+	dests  map[string]string // label to destination
+	byDest map[string]string // destination to numeric label
+}
+
+// newLinkReferences returns an empty set of references labeled by style.
+func newLinkReferences(style ReferenceLabelStyle) *linkReferences {
+	return &linkReferences{
+		style:  style,
+		dests:  make(map[string]string),
+		byDest: make(map[string]string),
+	}
+}
+
+// label returns the reference label for a link to dest with the given
+// inner HTML, adding a definition on first use.
+//
+// Postconditions:
+//   - NumericLabels numbers destinations from 1 and reuses the number
+//     of a destination seen before
+//   - TextLabels slugifies the link text, reusing a label that already
+//     points to dest and otherwise appending -2, -3, ... to collisions
+//   - Text without slug characters, such as an image-only link, uses "link"
+func (r *linkReferences) label(text, dest string) string {
+	if r.style == NumericLabels {
+		if label, ok := r.byDest[dest]; ok {
+			return label
+		}
+		label := strconv.Itoa(len(r.labels) + 1)
+		r.byDest[dest] = label
+		r.add(label, dest)
+		return label
+	}
+
+	plain := strings.Join(strings.Fields(decodeHTMLEntities(reHtmlTag.ReplaceAllString(text, ""))), " ")
+	base := githubSlug(plain)
+	if base == "" {
+		base = "link"
+	}
+	label := base
+	for n := 2; ; n++ {
+		existing, ok := r.dests[label]
+		if !ok {
+			r.add(label, dest)
+			return label
+		}
+		if existing == dest {
+			return label
+		}
+		label = base + "-" + strconv.Itoa(n)
+	}
+}
+
+// add records a new label for dest.
+func (r *linkReferences) add(label, dest string) {
+	r.labels = append(r.labels, label)
+	r.dests[label] = dest
+}
+
+// definitions returns the "[label]: dest" lines for all labels, preceded
+// by a blank line, or an empty string if there are none.
+func (r *linkReferences) definitions() string {
+	if len(r.labels) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n")
+	for _, label := range r.labels {
+		sb.WriteString("\n[" + label + "]: " + r.dests[label])
+	}
+	return sb.String()
+}
+
+// truncateWithDefinitions is truncateMarkdown for Markdown whose reference
+// definitions were gathered at its end by definitions.
+//
+// Preconditions:
+//   - limit is greater than 0
+//
+// Invariants:
+//   - The definitions do not count toward limit
+//
+// Postconditions:
+//   - The body before the definitions is truncated by truncateMarkdown
+//   - Only the definitions of labels still used in the body are kept
+func truncateWithDefinitions(md string, limit int) string {
+	i := strings.LastIndex(md, "\n\n")
+	if i < 0 {
+		return truncateMarkdown(md, limit)
+	}
+	lines := strings.Split(md[i+2:], "\n")
+	for _, line := range lines {
+		if !reDefinition.MatchString(line) {
+			return truncateMarkdown(md, limit)
+		}
+	}
+
+	body := truncateMarkdown(md[:i], limit)
+	used := make(map[string]bool)
+	for _, m := range reReferenceLink.FindAllStringSubmatch(body, -1) {
+		used[m[1]] = true
+	}
+	var kept []string
+	for _, line := range lines {
+		if used[reDefinition.FindStringSubmatch(line)[1]] {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		return body
+	}
+	return body + "\n\n" + strings.Join(kept, "\n")
+}
@@ -0,0 +1,128 @@
+// Package main provides conversion diagnostics.
+//
+// This file defines ConversionReport, which collects non-fatal problems
+// found while converting. Diagnostics never change the Markdown output;
+// they only explain it.
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// reOpenTagName matches an opening tag and captures its name.
+// Closing tags, comments, and doctypes do not match.
+var reOpenTagName = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// ConversionReport describes what happened during a conversion.
+//
+// Invariants:
+//   - Entries are recorded in document order
+//   - The zero value is an empty report
+type ConversionReport struct {
+	// UnconvertedTags lists the lowercase names of HTML tags that had no
+	// Markdown equivalent and were stripped, without duplicates.
+	UnconvertedTags []string
+
+	// Warnings lists human-readable descriptions of input that converted
+	// but may render incorrectly, such as tables with ragged rows.
+	Warnings []string
+
+	// Elements counts the HTML elements converted to Markdown.
+	Elements ElementCounts
+}
+
+// ElementCounts counts the elements of a document by the Markdown
+// construct they become.
+type ElementCounts struct {
+	Headings   int // <h1> to <h6>
+	Links      int // <a>
+	Images     int // <img>
+	Tables     int // top-level <table> converted to pipe tables
+	CodeBlocks int // <pre>, including syntax highlighter tables
+}
+
+// ConvertWithReport transforms an HTML string into Markdown format and
+// reports diagnostics about the conversion.
+//
+// Preconditions:
+//   - html can be any string, including empty string
+//   - opts may be the zero value
+//
+// Invariants:
+//   - Diagnostics never stop or alter the conversion
+//
+// Postconditions:
+//   - The returned Markdown is identical to ConvertWithOptions(html, opts)
+//   - The returned report lists warnings and stripped tags
+func ConvertWithReport(html string, opts Options) (string, ConversionReport) {
+	var report ConversionReport
+	md := convert(html, &opts, &report)
+	return md, report
+}
+
+// addWarning appends a formatted warning to the report.
+//
+// Preconditions:
+//   - r may be nil, in which case the warning is discarded
+func (r *ConversionReport) addWarning(format string, args ...any) {
+	if r == nil {
+		return
+	}
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// recordUnconvertedTags records the names of all HTML tags still present
+// in s, which cleanupOutput is about to strip.
+//
+// Preconditions:
+//   - s has been processed by all conversion functions
+//   - r may be nil, in which case nothing is recorded
+//
+// Postconditions:
+//   - Each tag name appears in UnconvertedTags at most once
+func (r *ConversionReport) recordUnconvertedTags(s string) {
+	if r == nil {
+		return
+	}
+	for _, m := range reOpenTagName.FindAllStringSubmatch(s, -1) {
+		name := strings.ToLower(m[1])
+		if !slices.Contains(r.UnconvertedTags, name) {
+			r.UnconvertedTags = append(r.UnconvertedTags, name)
+		}
+	}
+}
+
+// recordElements counts the elements of s that block and inline
+// conversion are about to convert.
+//
+// Preconditions:
+//   - s has been prepared for block conversion, so highlighter tables are
+//     already <pre> blocks and tables kept as HTML are escaped
+//   - r may be nil, in which case nothing is recorded
+//
+// Invariants:
+//   - Tables nested in another table are not counted, since they are
+//     flattened into their parent's cells
+func (r *ConversionReport) recordElements(s string) {
+	if r == nil {
+		return
+	}
+	r.Elements.Headings += len(reHeadingLevel.FindAllStringIndex(s, -1))
+	r.Elements.Links += len(reLink.FindAllStringIndex(s, -1))
+	r.Elements.Images += len(reImg.FindAllStringIndex(s, -1))
+	r.Elements.CodeBlocks += len(rePre.FindAllStringIndex(s, -1))
+	depth := 0
+	for _, m := range reTableTag.FindAllStringSubmatch(s, -1) {
+		if m[1] != "" {
+			depth = max(depth-1, 0)
+			continue
+		}
+		if depth == 0 {
+			r.Elements.Tables++
+		}
+		depth++
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConvertWithReport(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		html string
+		opts Options
+	}
+	tests := []struct {
+		name string
+		args args
+		want ConversionReport
+	}{
+		{
+			name: "変換可能なタグのみの場合に警告も未対応タグも記録されない",
+			args: args{html: "<h1>Title</h1><p>Text</p>"},
+			want: ConversionReport{Elements: ElementCounts{Headings: 1}},
+		},
+		{
+			name: "未対応タグがある場合に重複なしで記録される",
+			args: args{html: "<span>a</span><SPAN>b</SPAN><mark>c</mark>"},
+			want: ConversionReport{UnconvertedTags: []string{"span", "mark"}},
+		},
+		{
+			name: "テーブル行のセル数がヘッダと異なる場合に警告が記録される",
+			args: args{html: `<table>
+		<tr><th>A</th><th>B</th><th>C</th><th>D</th></tr>
+		<tr><td>1</td><td>2</td><td>3</td><td>4</td></tr>
+		<tr><td>1</td><td>2</td></tr>
+	</table>`},
+			want: ConversionReport{
+				Warnings: []string{"table 1 row 3 had 2 cells, header had 4"},
+				Elements: ElementCounts{Tables: 1},
+			},
+		},
+		{
+			name: "LineEndingが不明な値の場合に警告が記録される",
+			args: args{html: "<p>Text</p>", opts: Options{LineEnding: "CRLF"}},
+			want: ConversionReport{Warnings: []string{`unrecognized line ending "CRLF", using LF`}},
+		},
+		{
+			name: "変換される要素の種類ごとに数が記録される",
+			args: args{html: `<h1>T</h1><h2>S</h2>
+<p><a href="/a">a</a> <a href="/b"><img src="b.png" alt="b"></a></p>
+<pre><code>x</code></pre>
+<table><tr><td><table><tr><td>n</td></tr></table></td></tr></table>
+<table class="highlight"><tr><td class="linenos">1</td><td>y</td></tr></table>`},
+			want: ConversionReport{Elements: ElementCounts{Headings: 2, Links: 2, Images: 1, Tables: 1, CodeBlocks: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			md, got := ConvertWithReport(tt.args.html, tt.args.opts)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ConvertWithReport() report mismatch (-want +got):\n%s", diff)
+			}
+			if want := ConvertWithOptions(tt.args.html, tt.args.opts); md != want {
+				t.Errorf("ConvertWithReport() markdown = %q, want %q", md, want)
+			}
+		})
+	}
+}
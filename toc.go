@@ -0,0 +1,199 @@
+// Package main provides table of contents generation.
+//
+// This file implements Options.GenerateTOC. Headings are collected from the
+// finished Markdown rather than the HTML, so the TOC reflects the output
+// exactly, including HeadingOffset and HeadingTransform. Each heading
+// becomes a link such as [Setup](#setup), nested under the link of the
+// preceding shallower heading.
+//
+// Anchors follow GitHub's heading slug rules so the links work when the
+// Markdown is rendered there. A heading given an explicit id by
+// Options.HeadingAnchors links to that id instead.
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// escTOC marks where a <!-- TOC --> comment asked for the table of contents.
+const escTOC = "\x00TOC\x00"
+
+var (
+	// reTOCMarker matches the comment marking where the TOC is inserted.
+	reTOCMarker = regexp.MustCompile(`(?i)<!--\s*TOC\s*-->`)
+
+	// reATXHeading matches an ATX heading line and captures its level and text.
+	reATXHeading = regexp.MustCompile(`^(#{1,6}) +(.+?)\s*$`)
+
+	// reSetextUnderline matches the underline of a Setext heading.
+	reSetextUnderline = regexp.MustCompile(`^(=+|-+)\s*$`)
+
+	// reMarkdownLink matches an inline link or image and captures its text.
+	reMarkdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+
+	// reHeadingIDAttr matches the trailing {#id} written by AnchorAttr.
+	reHeadingIDAttr = regexp.MustCompile(`\s*\{#([^\s{}]+)\}$`)
+
+	// reHeadingIDLine matches the <a id="id"></a> line written by AnchorHTML.
+	reHeadingIDLine = regexp.MustCompile(`^<a id="([^"]*)"></a>$`)
+)
+
+// tocHeading is a heading collected for the table of contents.
+type tocHeading struct {
+	level int
+	text  string // plain text without Markdown formatting
+	id    string // explicit anchor from HeadingAnchors, or empty
+}
+
+// markTOC replaces each <!-- TOC --> comment with escTOC on its own line.
+//
+// Preconditions:
+//   - s is the HTML before comments are handled
+func markTOC(s string) string {
+	return reTOCMarker.ReplaceAllString(s, "\n\n"+escTOC+"\n\n")
+}
+
+// insertTOC inserts a table of contents for the headings of md.
+//
+// Preconditions:
+//   - md is the finished Markdown, possibly containing escTOC markers
+//
+// Invariants:
+//   - Headings inside fenced code blocks are ignored
+//   - Nesting is relative to the shallowest heading level
+//
+// Postconditions:
+//   - The TOC replaces the first escTOC marker, or is prepended to md
+//     followed by a blank line when there is no marker
+//   - Remaining markers are removed
+//   - If md has no headings, only the markers are removed
+func insertTOC(md string, opts *Options) string {
+	headings := collectHeadings(md)
+	toc := formatTOC(headings, opts)
+	if !strings.Contains(md, escTOC) && toc != "" {
+		md = escTOC + "\n\n" + md
+	}
+	md = strings.Replace(md, escTOC, toc, 1)
+	md = strings.ReplaceAll(md, escTOC, "")
+	md = reMultiNewline.ReplaceAllString(md, "\n\n")
+	return strings.TrimSpace(md)
+}
+
+// collectHeadings returns the ATX and Setext headings of md in order.
+func collectHeadings(md string) []tocHeading {
+	var headings []tocHeading
+	lines := strings.Split(md, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := reATXHeading.FindStringSubmatch(line); m != nil {
+			headings = append(headings, newTOCHeading(len(m[1]), m[2], lines[:i]))
+			continue
+		}
+		// A Setext underline directly follows its text; a "---" after a
+		// blank line is a horizontal rule
+		if i > 0 && strings.TrimSpace(lines[i-1]) != "" && reSetextUnderline.MatchString(line) &&
+			!reATXHeading.MatchString(lines[i-1]) {
+			level := 1
+			if line[0] == '-' {
+				level = 2
+			}
+			headings = append(headings, newTOCHeading(level, lines[i-1], lines[:i-1]))
+		}
+	}
+	return headings
+}
+
+// newTOCHeading returns the heading with text at level, where before holds
+// the lines preceding the heading.
+//
+// Postconditions:
+//   - A trailing {#id} is removed from the text and used as the id
+//   - Otherwise an <a id="id"></a> line directly before the heading
+//     gives the id
+func newTOCHeading(level int, text string, before []string) tocHeading {
+	h := tocHeading{level: level}
+	if m := reHeadingIDAttr.FindStringSubmatchIndex(text); m != nil {
+		h.id = text[m[2]:m[3]]
+		text = text[:m[0]]
+	} else if len(before) > 0 {
+		if m := reHeadingIDLine.FindStringSubmatch(before[len(before)-1]); m != nil {
+			h.id = m[1]
+		}
+	}
+	h.text = plainHeadingText(text)
+	return h
+}
+
+// plainHeadingText removes Markdown formatting from heading text,
+// keeping the text of links and images.
+func plainHeadingText(s string) string {
+	s = reMarkdownLink.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "", "*", "").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// formatTOC renders headings as a nested bullet list of anchor links.
+//
+// Postconditions:
+//   - Each heading is indented two spaces per level below the shallowest
+//   - A heading with an explicit id links to it unchanged
+//   - Other anchors are unique; repeated slugs get -1, -2, ... suffixes
+//   - Returns an empty string if headings is empty
+func formatTOC(headings []tocHeading, opts *Options) string {
+	if len(headings) == 0 {
+		return ""
+	}
+	top := 6
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+
+	seen := make(map[string]int)
+	var sb strings.Builder
+	for i, h := range headings {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		anchor := h.id
+		if anchor == "" {
+			anchor = githubSlug(h.text)
+			if n, ok := seen[anchor]; ok {
+				seen[anchor] = n + 1
+				anchor += "-" + strconv.Itoa(n+1)
+			} else {
+				seen[anchor] = 0
+			}
+		}
+		sb.WriteString(strings.Repeat("  ", h.level-top))
+		sb.WriteString(opts.bulletMarker() + " [" + h.text + "](#" + anchor + ")")
+	}
+	return sb.String()
+}
+
+// githubSlug returns the anchor GitHub generates for a heading.
+//
+// Postconditions:
+//   - Letters are lowercased; letters, digits, hyphens, and underscores
+//     are kept; spaces become hyphens; other characters are removed
+func githubSlug(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,212 @@
+// Package main provides URL rewriting for links and images.
+//
+// URLs are rewritten just before they are emitted as Markdown, so the
+// same rules apply to <a href> and <img src>. With Options.Autolink,
+// bare URLs in text also become CommonMark autolinks.
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// reBareURL matches an http or https URL in text. Entities are part
+	// of the match, since text in HTML writes & as &amp;.
+	reBareURL = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'\x00]+`)
+
+	// reAutolinkTag matches the tags tracked by autolinkURLs: any tag, or
+	// a table kept as escaped HTML by Options.ComplexTableAsHTML.
+	reAutolinkTag = regexp.MustCompile(`(?i)<(/?)([a-z][a-z0-9]*)\b[^>]*>|` + escLT + `(/?)(table)` + escGT)
+)
+
+// autolinkSkipTags lists the elements whose text is never autolinked:
+// existing links, code, and scripts.
+var autolinkSkipTags = map[string]bool{
+	"a": true, "pre": true, "code": true, "kbd": true, "tt": true, "samp": true,
+	"script": true, "style": true, "textarea": true,
+}
+
+// defaultTrackingParams lists the query parameter prefixes removed when
+// Options.StripTrackingParams is set and Options.TrackingParams is empty.
+var defaultTrackingParams = []string{"utm_", "fbclid", "gclid"}
+
+// rewriteURL applies the URL options in opts to raw.
+//
+// Preconditions:
+//   - raw is an attribute value taken from the HTML
+//   - opts is non-nil
+//
+// Postconditions:
+//   - Returns raw unchanged when no URL option is enabled
+func rewriteURL(raw string, opts *Options) string {
+	if opts.BaseURL != "" {
+		raw = resolveURL(raw, opts.BaseURL)
+	}
+	if opts.StripTrackingParams {
+		// Attribute values commonly separate parameters with &amp;
+		raw = stripTrackingParams(decodeHTMLEntities(raw), opts.TrackingParams)
+	}
+	return raw
+}
+
+// resolveURL resolves raw against base.
+//
+// Invariants:
+//   - Fragment-only references such as "#section" stay in-page links
+//
+// Postconditions:
+//   - Returns the absolute URL for relative references
+//   - Returns raw unchanged if either URL cannot be parsed
+func resolveURL(raw, base string) string {
+	if strings.HasPrefix(raw, "#") {
+		return raw
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return raw
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return b.ResolveReference(ref).String()
+}
+
+// stripTrackingParams removes query parameters whose name starts with one
+// of prefixes from raw.
+//
+// Preconditions:
+//   - prefixes may be empty, in which case defaultTrackingParams is used
+//
+// Invariants:
+//   - The order and encoding of the remaining parameters are preserved
+//   - Parameter names are compared case-insensitively
+//
+// Postconditions:
+//   - Returns raw unchanged if it cannot be parsed or has no query
+//   - The "?" is removed when no parameters remain
+func stripTrackingParams(raw string, prefixes []string) string {
+	if len(prefixes) == 0 {
+		prefixes = defaultTrackingParams
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	var kept []string
+	for param := range strings.SplitSeq(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !hasAnyPrefix(strings.ToLower(name), prefixes) {
+			kept = append(kept, param)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes, ignoring case.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// autolinkURLs wraps bare URLs in the text of s as <url> autolinks.
+//
+// Preconditions:
+//   - s is HTML before block conversion, so <pre>, <code>, and <a> are intact
+//
+// Invariants:
+//   - Text inside autolinkSkipTags and inside tables kept as HTML is
+//     never changed, nor are attribute values
+//   - A URL already written as a Markdown link destination or autolink
+//     in the text, after "](" or "&lt;", is left unchanged
+//   - Trailing sentence punctuation, and a ) without a matching (, is
+//     not part of the URL
+//
+// Postconditions:
+//   - Each URL becomes <url> written with escape placeholders, rewritten
+//     according to opts by rewriteURL
+func autolinkURLs(s string, opts *Options) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	skip, escaped := 0, 0
+	last := 0
+	for _, m := range reAutolinkTag.FindAllStringSubmatchIndex(s, -1) {
+		if skip == 0 && escaped == 0 {
+			sb.WriteString(autolinkText(s[last:m[0]], opts))
+		} else {
+			sb.WriteString(s[last:m[0]])
+		}
+		sb.WriteString(s[m[0]:m[1]])
+		last = m[1]
+
+		closing := m[3] > m[2] || m[7] > m[6]
+		switch {
+		case m[8] >= 0 && closing:
+			escaped = max(escaped-1, 0)
+		case m[8] >= 0:
+			escaped++
+		case !autolinkSkipTags[strings.ToLower(s[m[4]:m[5]])]:
+		case closing:
+			skip = max(skip-1, 0)
+		default:
+			skip++
+		}
+	}
+	if skip == 0 && escaped == 0 {
+		sb.WriteString(autolinkText(s[last:], opts))
+	} else {
+		sb.WriteString(s[last:])
+	}
+	return sb.String()
+}
+
+// autolinkText wraps the bare URLs in text, which holds no tags.
+func autolinkText(text string, opts *Options) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range reBareURL.FindAllStringIndex(text, -1) {
+		before := text[:m[0]]
+		if strings.HasSuffix(before, "](") || strings.HasSuffix(before, "&lt;") || strings.HasSuffix(before, "&LT;") {
+			continue
+		}
+		u := trimURL(text[m[0]:m[1]])
+		if strings.HasSuffix(u, "://") {
+			continue
+		}
+		sb.WriteString(before[last:] + escLT + rewriteURL(u, opts) + escGT)
+		last = m[0] + len(u)
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// trimURL removes the characters at the end of a URL matched in text
+// that belong to the surrounding sentence or markup.
+func trimURL(u string) string {
+	for _, ref := range []string{"&lt;", "&gt;", "&quot;", "&LT;", "&GT;", "&QUOT;"} {
+		if i := strings.Index(u, ref); i >= 0 {
+			u = u[:i]
+		}
+	}
+	for {
+		trimmed := strings.TrimRight(u, ".,;:!?*_~")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
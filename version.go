@@ -0,0 +1,31 @@
+// Package main provides version information.
+//
+// The version is injected at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.3"
+//
+// Without injection, the module version recorded by "go install" is used.
+package main
+
+import "runtime/debug"
+
+// version is set at build time via -ldflags "-X main.version=...".
+var version = ""
+
+// Version returns the version of the converter that produced the output.
+//
+// Invariants:
+//   - A version injected via ldflags takes precedence
+//
+// Postconditions:
+//   - Returns the injected version, else the module version from the
+//     build info, else "(devel)"
+func Version() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
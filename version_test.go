@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestVersion(t *testing.T) {
+	orig := version
+	t.Cleanup(func() { version = orig })
+
+	version = "v1.2.3"
+	if got := Version(); got != "v1.2.3" {
+		t.Errorf("Version() = %q, want %q", got, "v1.2.3")
+	}
+
+	version = ""
+	if got := Version(); got == "" {
+		t.Error("Version() should not be empty without an injected version")
+	}
+}